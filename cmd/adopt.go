@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/shell"
+	"fmt"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+)
+
+var adoptYes bool
+
+var adoptCmd = &cobra.Command{
+	Use:   "adopt",
+	Short: "Move lines installers appended to your RC file into modules",
+	Long: `Find snippets that tools like nvm, conda, rustup or sdkman added to your RC
+file outside the dotwaifu block, move them into a module and remove them from
+the RC file.
+
+Only lines that were not in the backup taken by 'dotwaifu init' are picked up.
+Each snippet gets a suggested target: a snippet mentioning flutter goes to
+projects/flutter, one setting up nvm goes to projects/node, and anything
+unrecognised goes to a core module.
+
+Examples:
+  dotwaifu adopt        # Review each snippet
+  dotwaifu adopt --yes  # Accept every suggestion`,
+	Run: runAdopt,
+}
+
+const (
+	adoptAccept = "Move it to the suggested module"
+	adoptChoose = "Choose another module"
+	adoptLeave  = "Leave it in the RC file"
+	adoptCore   = "core"
+	adoptNew    = "new project..."
+)
+
+func init() {
+	adoptCmd.Flags().BoolVarP(&adoptYes, "yes", "y", false, "Accept every suggested target without prompting")
+}
+
+func runAdopt(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+
+	if cfg.DetectedShell != "zsh" && cfg.DetectedShell != "bash" {
+		fmt.Println("adopt only works with zsh and bash RC files.")
+		return
+	}
+
+	rcPath := shell.GetRCFilePath(cfg.DetectedShell)
+	content, err := os.ReadFile(rcPath)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", rcPath, err)
+		return
+	}
+
+	items, err := shell.FindAdoptable(cfg.DetectedShell)
+	if err != nil {
+		fmt.Printf("Error scanning %s: %v\n", rcPath, err)
+		return
+	}
+
+	if len(items) == 0 {
+		fmt.Printf("✅ Nothing to adopt, %s only contains the dotwaifu block and your original lines.\n", rcPath)
+		return
+	}
+
+	fmt.Printf("Found %d snippet(s) added to %s:\n", len(items), rcPath)
+
+	var adopted []shell.ImportItem
+	for _, item := range items {
+		fmt.Printf("\nLines %d-%d → %s\n", item.StartLine, item.EndLine, item.Target())
+		for _, line := range item.Lines {
+			fmt.Printf("  │ %s\n", line)
+		}
+
+		if !adoptYes {
+			keep, err := chooseAdoptTarget(cfg, &item)
+			if err != nil {
+				fmt.Println("Adopt cancelled.")
+				return
+			}
+			if !keep {
+				continue
+			}
+		}
+
+		moduleContent := fmt.Sprintf("\n# Adopted from %s\n%s", rcPath, item.Content())
+		if err := shell.AppendToModule(item.Project, item.Category, moduleContent); err != nil {
+			fmt.Printf("Error writing %s: %v\n", item.Target(), err)
+			return
+		}
+		fmt.Printf("✓ Moved to %s\n", item.Target())
+		adopted = append(adopted, item.ImportItem)
+	}
+
+	if len(adopted) == 0 {
+		fmt.Println("\nNothing was moved.")
+		return
+	}
+
+	if err := os.WriteFile(rcPath, []byte(shell.StripItems(string(content), adopted)), 0644); err != nil {
+		fmt.Printf("Error updating %s: %v\n", rcPath, err)
+		return
+	}
+
+	fmt.Printf("\n✅ Adopted %d snippet(s) and removed them from %s\n", len(adopted), rcPath)
+	fmt.Println("Run 'dotwaifu reload' to apply the changes.")
+}
+
+// chooseAdoptTarget asks what to do with a snippet, updating its target when
+// the user picks a different module. It returns false if the snippet should
+// stay in the RC file.
+func chooseAdoptTarget(cfg *config.Config, item *shell.AdoptItem) (bool, error) {
+	var action string
+	prompt := &survey.Select{
+		Message: "What should happen to this snippet?",
+		Options: []string{adoptAccept, adoptChoose, adoptLeave},
+	}
+	if err := survey.AskOne(prompt, &action); err != nil {
+		return false, err
+	}
+
+	switch action {
+	case adoptLeave:
+		return false, nil
+	case adoptAccept:
+		return true, nil
+	}
+
+	projects, err := shell.ListProjects()
+	if err != nil {
+		return false, err
+	}
+
+	defaultProject := adoptCore
+	if item.Project != "" {
+		defaultProject = item.Project
+		if !contains(projects, item.Project) {
+			projects = append(projects, item.Project)
+		}
+	}
+
+	var project string
+	projectPrompt := &survey.Select{
+		Message: "Move it to:",
+		Options: append(append([]string{adoptCore}, projects...), adoptNew),
+		Default: defaultProject,
+	}
+	if err := survey.AskOne(projectPrompt, &project); err != nil {
+		return false, err
+	}
+
+	switch project {
+	case adoptCore:
+		project = ""
+	case adoptNew:
+		namePrompt := &survey.Input{Message: "Project name:"}
+		if err := survey.AskOne(namePrompt, &project, survey.WithValidator(validateProjectAnswer)); err != nil {
+			return false, err
+		}
+	}
+
+	var category string
+	categoryPrompt := &survey.Select{
+		Message: "Module:",
+		Options: moduleChoices(cfg),
+		Default: item.Category,
+	}
+	if err := survey.AskOne(categoryPrompt, &category); err != nil {
+		return false, err
+	}
+
+	item.Project = project
+	item.Category = category
+	return true, nil
+}
+
+// validateProjectAnswer is a survey validator for the name of a new project.
+func validateProjectAnswer(answer interface{}) error {
+	name, _ := answer.(string)
+	return shell.ValidateProjectName(name)
+}
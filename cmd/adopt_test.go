@@ -0,0 +1,14 @@
+package cmd
+
+import "testing"
+
+func TestValidateProjectAnswer(t *testing.T) {
+	for _, name := range []string{"", ".hidden", "a/b", `a\b`} {
+		if err := validateProjectAnswer(name); err == nil {
+			t.Errorf("validateProjectAnswer(%q) accepted the name", name)
+		}
+	}
+	if err := validateProjectAnswer("flutter"); err != nil {
+		t.Errorf("validateProjectAnswer(flutter) = %v", err)
+	}
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/shell"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	bisectTest    string
+	bisectTimeout time.Duration
+)
+
+var bisectCmd = &cobra.Command{
+	Use:   "bisect",
+	Short: "Find the module that breaks or hangs new shells",
+	Long: `Start your shell again and again with only the first part of the modules
+loaded, in load order, and narrow down to the first module that makes a test
+fail. The test is a command run in the new interactive shell; it fails when it
+exits non-zero or does not finish within the timeout. The default test, true,
+finds modules that make the shell hang.
+
+To get a working shell in the meantime, start one with
+  DOTWAIFU_SAFE=1 zsh                 # no modules at all
+  DOTWAIFU_SKIP=core/scripts zsh      # without some modules or projects
+
+Examples:
+  dotwaifu bisect
+  dotwaifu bisect --timeout 3s
+  dotwaifu bisect --test 'command -v kubectl'
+  dotwaifu bisect --test '[ "$EDITOR" = nvim ]'`,
+	Args: cobra.NoArgs,
+	Run:  runBisect,
+}
+
+func init() {
+	bisectCmd.Flags().StringVar(&bisectTest, "test", "true", "Command that succeeds in a good shell")
+	bisectCmd.Flags().DurationVar(&bisectTimeout, "timeout", 10*time.Second, "Count a shell that takes longer than this as failing")
+}
+
+func skipList(modules []shell.Module) string {
+	var rels []string
+	for _, module := range modules {
+		rels = append(rels, module.RelPath())
+	}
+	return strings.Join(rels, ",")
+}
+
+// editCommand suggests how to open module in the editor.
+func editCommand(module shell.Module) string {
+	if module.Project == "" {
+		return "dotwaifu edit " + module.Type
+	}
+	return "dotwaifu edit " + module.Type + " " + module.Project
+}
+
+// preserveFile returns a function that puts path back the way it is now,
+// including its modification time, or removes it if it does not exist.
+func preserveFile(path string) func() {
+	info, err := os.Stat(path)
+	if err != nil {
+		return func() { os.Remove(path) }
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return func() {}
+	}
+	return func() {
+		if os.WriteFile(path, content, info.Mode()) == nil {
+			os.Chtimes(path, info.ModTime(), info.ModTime())
+		}
+	}
+}
+
+func runBisect(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	shellName := cfg.DetectedShell
+	if shellName != "zsh" && shellName != "bash" {
+		fmt.Printf("Bisecting works with zsh and bash, not %s\n", shellName)
+		os.Exit(1)
+	}
+
+	order, err := shell.LoadOrder()
+	if err != nil {
+		fmt.Printf("Error resolving the load order: %v\n", err)
+		os.Exit(1)
+	}
+	var modules []shell.Module
+	for _, module := range order {
+		if !module.HasConflict() {
+			modules = append(modules, module)
+		}
+	}
+	if len(modules) == 0 {
+		fmt.Println("No modules to bisect.")
+		return
+	}
+
+	// The shells started here would replace the status of the last real start
+	restoreStatus := preserveFile(shell.GetStatusPath())
+	defer restoreStatus()
+
+	fmt.Printf("Testing '%s' in new %s shells (timeout %s)\n\n", bisectTest, shellName, bisectTimeout)
+
+	// run starts a shell with env and reports whether the test passed
+	run := func(label string, env ...string) bool {
+		fmt.Printf("  %-50s ", label)
+		if err := shell.StartupTest(shellName, bisectTest, bisectTimeout, env...); err != nil {
+			fmt.Printf("fails (%v)\n", err)
+			return false
+		}
+		fmt.Println("ok")
+		return true
+	}
+
+	if run(fmt.Sprintf("all %d module(s)", len(modules))) {
+		fmt.Println("\n✓ The test passes with every module loaded, nothing to bisect.")
+		return
+	}
+	if !run("no modules (DOTWAIFU_SAFE=1)", "DOTWAIFU_SAFE=1") {
+		fmt.Println("\nThe test fails even without any modules, so the problem is elsewhere in your RC file or the test itself.")
+		restoreStatus()
+		os.Exit(1)
+	}
+
+	// The first good modules pass, the first bad ones fail
+	good, bad := 0, len(modules)
+	for bad-good > 1 {
+		mid := (good + bad) / 2
+		if run(fmt.Sprintf("first %d, through %s", mid, modules[mid-1].RelPath()), "DOTWAIFU_SKIP="+skipList(modules[mid:])) {
+			good = mid
+		} else {
+			bad = mid
+		}
+	}
+
+	culprit := modules[bad-1]
+	fmt.Printf("\n✗ %s makes the test fail\n", culprit.RelPath())
+	fmt.Printf("The test passes with the %d module(s) loaded before it.\n\n", bad-1)
+	fmt.Printf("  Edit it:           %s\n", editCommand(culprit))
+	fmt.Printf("  Shell without it:  DOTWAIFU_SKIP=%s %s\n", culprit.RelPath(), shellName)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"dotwaifu/internal/shell"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// captureStdout returns what fn prints on stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+func TestBisect(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	newMachine(t, map[string]string{
+		"core/env.sh":          "export EDITOR=vim\n",
+		"core/paths.sh":        "export BROKEN=1\n",
+		"core/scripts.sh":      "export SCRIPTS=1\n",
+		"projects/rust/env.sh": "export CARGO_HOME=/opt/cargo\n",
+	})
+	if err := shell.CreateNewRC("bash"); err != nil {
+		t.Fatal(err)
+	}
+	if err := shell.GenerateLoader(); err != nil {
+		t.Fatal(err)
+	}
+	status := []byte("core/env.sh\t1\tfrom the last real start\n")
+	if err := os.WriteFile(shell.GetStatusPath(), status, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	setFlag(t, &bisectTest, `[ -z "$BROKEN" ]`)
+	setFlag(t, &bisectTimeout, 5*time.Second)
+	out := captureStdout(t, func() { runBisect(nil, nil) })
+	if !strings.Contains(out, "✗ core/paths.sh makes the test fail") {
+		t.Errorf("bisect output:\n%s", out)
+	}
+
+	if got, _ := os.ReadFile(shell.GetStatusPath()); string(got) != string(status) {
+		t.Errorf("status after bisect = %q", got)
+	}
+}
+
+func TestBisectFindsLazyModule(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	newMachine(t, map[string]string{
+		"core/env.sh":   "export EDITOR=vim\n",
+		"core/ls.sh":    "# dotwaifu: lazy=ls\nls() { echo broken; }\n",
+		"core/paths.sh": "export PATHS=1\n",
+	})
+	if err := shell.CreateNewRC("bash"); err != nil {
+		t.Fatal(err)
+	}
+	if err := shell.GenerateLoader(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Leaving out a lazy module also leaves out its stubs
+	setFlag(t, &bisectTest, `[ "$(type -t ls)" != function ]`)
+	setFlag(t, &bisectTimeout, 5*time.Second)
+	out := captureStdout(t, func() { runBisect(nil, nil) })
+	if !strings.Contains(out, "✗ core/ls.sh makes the test fail") {
+		t.Errorf("bisect output:\n%s", out)
+	}
+}
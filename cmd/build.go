@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/shell"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	buildCheck  bool
+	buildRemove bool
+	buildQuiet  bool
+)
+
+var buildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Bundle all modules into one file for faster shell startup",
+	Long: `Concatenate every enabled module, in load order, into a single cached
+~/.config/dotwaifu/state/bundle.sh. While the bundle exists your RC file
+sources only that file, which helps on slow or network home directories.
+For zsh the bundle is also compiled with zcompile.
+
+dotwaifu edit, reload, sync and project commands keep the bundle up to date.
+When a module changes some other way, e.g. after a git pull, the next shell
+notices that it is newer than the bundle and rebuilds it.
+
+Examples:
+  dotwaifu build           # Build the bundle and use it from now on
+  dotwaifu build --check   # Exit 1 if the bundle is out of date
+  dotwaifu build --remove  # Go back to sourcing modules one by one`,
+	Args: cobra.NoArgs,
+	Run:  runBuild,
+}
+
+func init() {
+	buildCmd.Flags().BoolVar(&buildCheck, "check", false, "Only report whether the bundle is up to date")
+	buildCmd.Flags().BoolVar(&buildRemove, "remove", false, "Delete the bundle and stop bundling")
+	buildCmd.Flags().BoolVarP(&buildQuiet, "quiet", "q", false, "Only print errors")
+}
+
+func runBuild(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if cfg.DetectedShell == "" {
+		fmt.Println("No shell detected. Please run 'dotwaifu init' first.")
+		os.Exit(1)
+	}
+
+	if cfg.DetectedShell != "zsh" && cfg.DetectedShell != "bash" {
+		fmt.Printf("Bundling is only available for zsh and bash; %s already loads generated copies of your modules.\n", cfg.DetectedShell)
+		os.Exit(1)
+	}
+
+	switch {
+	case buildRemove:
+		if err := shell.RemoveBundle(); err != nil {
+			fmt.Printf("Error removing the bundle: %v\n", err)
+			os.Exit(1)
+		}
+		if !buildQuiet {
+			fmt.Println("✓ Bundle removed; modules are sourced one by one again")
+		}
+	case buildCheck:
+		reportBundleStatus(cfg.DetectedShell)
+	default:
+		writeBundle(cfg.DetectedShell)
+	}
+}
+
+func reportBundleStatus(shellName string) {
+	if !shell.BundleExists() {
+		if !buildQuiet {
+			fmt.Println("No bundle; modules are sourced one by one. Run 'dotwaifu build' to create one.")
+		}
+		return
+	}
+
+	current, err := shell.BundleIsCurrent(shellName)
+	if err != nil {
+		fmt.Printf("Error checking the bundle: %v\n", err)
+		os.Exit(1)
+	}
+	if !current {
+		fmt.Printf("%s is out of date, run 'dotwaifu build'\n", shell.GetBundlePath())
+		os.Exit(1)
+	}
+	if !buildQuiet {
+		fmt.Printf("✓ %s is up to date\n", shell.GetBundlePath())
+	}
+}
+
+func writeBundle(shellName string) {
+	if err := shell.GenerateLoader(); err != nil {
+		fmt.Printf("Error compiling modules: %v\n", err)
+		os.Exit(1)
+	}
+	if err := shell.BuildBundle(shellName); err != nil {
+		fmt.Printf("Error building the bundle: %v\n", err)
+		os.Exit(1)
+	}
+
+	var compileErr error
+	if shellName == "zsh" {
+		compileErr = shell.CompileBundle()
+	}
+
+	if buildQuiet {
+		return
+	}
+
+	fmt.Printf("✓ Built %s\n", shell.GetBundlePath())
+	if compileErr != nil {
+		fmt.Printf("Warning: %v; zsh will source the bundle uncompiled\n", compileErr)
+	} else if shellName == "zsh" {
+		fmt.Println("✓ Compiled it with zcompile")
+	}
+
+	if integration, err := shell.CheckIntegration(shellName); err == nil {
+		switch integration.Status {
+		case shell.IntegrationOutdated, shell.IntegrationModified:
+			fmt.Printf("Note: The dotwaifu loader in %s is %s and may not use the bundle, run 'dotwaifu repair'.\n", shell.GetRCFilePath(shellName), integration.Status)
+		}
+	}
+	fmt.Println("Open a new terminal to use it.")
+}
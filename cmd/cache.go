@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"dotwaifu/internal/shell"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the cached output of slow setup commands",
+	Long: `Lines like eval "$(pyenv init -)" run the command on every shell start.
+Write them as
+
+  dotwaifu_cache_eval pyenv init -
+
+instead: the output is saved under ~/.config/dotwaifu/state/cache the first
+time and sourced from there afterwards. It is refreshed when the command's
+binary changes, e.g. after an upgrade. Only use it for commands whose output
+does not depend on the current directory or environment.
+
+Examples:
+  dotwaifu cache list
+  dotwaifu cache clear pyenv
+  dotwaifu cache clear`,
+}
+
+var cacheListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List cached command output",
+	Args:  cobra.NoArgs,
+	Run:   runCacheList,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear [command]",
+	Short: "Delete cached output so it is regenerated on the next shell start",
+	Args:  cobra.MaximumNArgs(1),
+	Run:   runCacheClear,
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheListCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+}
+
+func runCacheList(cmd *cobra.Command, args []string) {
+	entries, err := shell.ListCache()
+	if err != nil {
+		fmt.Printf("Error reading the cache: %v\n", err)
+		return
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("Nothing cached yet. Replace eval \"$(tool init -)\" with 'dotwaifu_cache_eval tool init -' in a module.")
+		return
+	}
+
+	for _, entry := range entries {
+		status := "cached " + entry.ModTime.Format("2006-01-02 15:04")
+		if entry.Stale() {
+			status = "stale, refreshed on the next shell start"
+		}
+		fmt.Printf("%-30s %-5s %7s  %s\n", entry.Command, entry.Shell, formatSize(entry.Size), status)
+		fmt.Printf("  %s\n", entry.Binary)
+	}
+}
+
+func formatSize(size int64) string {
+	if size < 1024 {
+		return fmt.Sprintf("%d B", size)
+	}
+	return fmt.Sprintf("%.1f KB", float64(size)/1024)
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) {
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	cleared, err := shell.ClearCache(name)
+	if err != nil {
+		fmt.Printf("Error clearing the cache: %v\n", err)
+		return
+	}
+
+	switch {
+	case cleared == 0 && name != "":
+		fmt.Printf("Nothing cached for %s\n", name)
+	case cleared == 0:
+		fmt.Println("The cache is already empty")
+	default:
+		fmt.Printf("✓ Cleared %d cached output(s); they are regenerated on the next shell start\n", cleared)
+	}
+}
@@ -0,0 +1,492 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/git"
+	"dotwaifu/internal/shell"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var doctorJSON bool
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check your dotwaifu setup for problems",
+	Long: `Run a series of health checks and report pass, warn or fail for each:
+
+  config       config.yaml exists and parses
+  integration  the dotwaifu block in your RC file exists, is well-formed and the
+               current loader version
+  editor       your preferred editor is on PATH
+  repository   the git repository behind 'dotwaifu sync' is readable
+  modules      every module passes a bash -n / zsh -n syntax check
+  load order   the after= directives of the modules resolve, and in what order
+  bundle       the bundle from 'dotwaifu build', if any, is up to date
+  startup      no module takes longer to load than profile_threshold_ms or
+               hangs; this sources the modules in a separate shell
+  last start   no module failed to load on the last shell start
+  projects     every project.yaml manifest parses
+  backups      no RC backups are left behind
+
+Exits with a non-zero status if any check fails.
+
+Examples:
+  dotwaifu doctor         # Human-readable report
+  dotwaifu doctor --json  # Machine-readable report`,
+	Run: runDoctor,
+}
+
+const (
+	checkPass = "pass"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+type checkResult struct {
+	Name    string   `json:"name"`
+	Status  string   `json:"status"`
+	Message string   `json:"message"`
+	Details []string `json:"details,omitempty"`
+}
+
+type doctorReport struct {
+	Checks  []checkResult  `json:"checks"`
+	Summary map[string]int `json:"summary"`
+}
+
+func init() {
+	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Print the report as JSON")
+}
+
+func runDoctor(cmd *cobra.Command, args []string) {
+	report := doctorReport{
+		Checks:  runDoctorChecks(),
+		Summary: map[string]int{checkPass: 0, checkWarn: 0, checkFail: 0},
+	}
+	for _, check := range report.Checks {
+		report.Summary[check.Status]++
+	}
+
+	if doctorJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Printf("Error encoding report: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+	} else {
+		printDoctorReport(report)
+	}
+
+	if report.Summary[checkFail] > 0 {
+		os.Exit(1)
+	}
+}
+
+func runDoctorChecks() []checkResult {
+	cfg, err := config.Load()
+	checks := []checkResult{checkConfig(err)}
+	if err != nil {
+		cfg = &config.Config{}
+	}
+
+	shellName := cfg.DetectedShell
+	if shellName == "" {
+		shellName = shell.DetectShell()
+	}
+
+	return append(checks,
+		checkIntegration(shellName),
+		checkEditor(cfg),
+		checkRepository(),
+		checkModules(shellName),
+		checkLoadOrder(),
+		checkBundle(shellName),
+		checkStartup(cfg, shellName),
+		checkLastStart(),
+		checkProjects(),
+		checkBackups(shellName),
+	)
+}
+
+func printDoctorReport(report doctorReport) {
+	symbols := map[string]string{checkPass: "✓", checkWarn: "⚠", checkFail: "✗"}
+
+	fmt.Println("dotwaifu doctor")
+	fmt.Println()
+	for _, check := range report.Checks {
+		fmt.Printf("  %s %-12s %s\n", symbols[check.Status], check.Name, check.Message)
+		for _, detail := range check.Details {
+			fmt.Printf("      %s\n", detail)
+		}
+	}
+
+	fmt.Printf("\n%d passed, %d warning(s), %d failed\n",
+		report.Summary[checkPass], report.Summary[checkWarn], report.Summary[checkFail])
+}
+
+func checkConfig(loadErr error) checkResult {
+	result := checkResult{Name: "config"}
+	configPath := config.GetConfigPath()
+
+	switch {
+	case loadErr != nil:
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("%s is invalid", configPath)
+		result.Details = []string{loadErr.Error()}
+	case !fileExists(configPath):
+		result.Status = checkWarn
+		result.Message = fmt.Sprintf("%s not found, run 'dotwaifu init'", configPath)
+	default:
+		result.Status = checkPass
+		result.Message = fmt.Sprintf("%s parses", configPath)
+	}
+	return result
+}
+
+func checkIntegration(shellName string) checkResult {
+	result := checkResult{Name: "integration"}
+	rcPath := shell.GetRCFilePath(shellName)
+
+	integration, err := shell.CheckIntegration(shellName)
+	if err != nil {
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("cannot read %s: %v", rcPath, err)
+		return result
+	}
+
+	switch integration.Status {
+	case shell.IntegrationCurrent:
+		result.Status = checkPass
+		result.Message = fmt.Sprintf("%s loads dotwaifu (loader v%d)", rcPath, integration.Version)
+	case shell.IntegrationOutdated:
+		result.Status = checkWarn
+		result.Message = fmt.Sprintf("the loader in %s is outdated, run 'dotwaifu repair'", rcPath)
+		if integration.Version == 0 {
+			result.Details = []string{fmt.Sprintf("unversioned loader, current is v%d", shell.LoaderVersion)}
+		} else {
+			result.Details = []string{fmt.Sprintf("loader v%d, current is v%d", integration.Version, shell.LoaderVersion)}
+		}
+	case shell.IntegrationModified:
+		result.Status = checkWarn
+		result.Message = fmt.Sprintf("the loader in %s was edited by hand, run 'dotwaifu repair'", rcPath)
+		result.Details = []string{"Move your changes into a module so they survive repairs"}
+	case shell.IntegrationMalformed:
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("the dotwaifu block in %s is malformed", rcPath)
+		result.Details = []string{"Expected exactly one start marker followed by one end marker"}
+	default:
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("%s does not load dotwaifu, run 'dotwaifu init'", rcPath)
+	}
+	return result
+}
+
+func checkEditor(cfg *config.Config) checkResult {
+	result := checkResult{Name: "editor"}
+
+	fields := strings.Fields(cfg.PreferredEditor)
+	if len(fields) == 0 {
+		result.Status = checkWarn
+		result.Message = "no preferred editor set, 'dotwaifu edit' will ask for one"
+		return result
+	}
+
+	path, err := exec.LookPath(fields[0])
+	if err != nil {
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("'%s' is not on PATH", fields[0])
+		return result
+	}
+
+	result.Status = checkPass
+	result.Message = fmt.Sprintf("'%s' resolves to %s", fields[0], path)
+	return result
+}
+
+func checkRepository() checkResult {
+	result := checkResult{Name: "repository"}
+
+	if !git.IsGitRepository() {
+		result.Status = checkWarn
+		result.Message = "not a git repository yet, 'dotwaifu sync' will create one"
+		return result
+	}
+
+	if err := git.CheckHealth(); err != nil {
+		result.Status = checkFail
+		result.Message = "the git repository is damaged"
+		result.Details = []string{err.Error()}
+		return result
+	}
+
+	if git.MergeInProgress() {
+		conflicts, _ := git.ListConflicts()
+		result.Status = checkWarn
+		result.Message = fmt.Sprintf("a sync merge is in progress with %d conflicted file(s), run 'dotwaifu sync'", len(conflicts))
+		result.Details = conflicts
+		return result
+	}
+
+	result.Status = checkPass
+	result.Message = "git repository is healthy"
+	if remote, err := git.GetRemoteURL(); err == nil && remote != "" {
+		result.Message += fmt.Sprintf(" (remote %s)", remote)
+	}
+	return result
+}
+
+func checkModules(shellName string) checkResult {
+	result := checkResult{Name: "modules"}
+	checker := shell.ModuleShell(shellName)
+
+	modules, err := shell.ListModules()
+	if err != nil {
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("cannot list modules: %v", err)
+		return result
+	}
+
+	if len(modules) == 0 {
+		result.Status = checkWarn
+		result.Message = "no modules found, run 'dotwaifu init'"
+		return result
+	}
+
+	result.Status = checkPass
+	for _, module := range modules {
+		if module.HasConflict() {
+			result.Details = append(result.Details, fmt.Sprintf("%s: skipped, unresolved sync conflict", module.RelPath()))
+			if result.Status == checkPass {
+				result.Status = checkWarn
+			}
+			continue
+		}
+
+		err := shell.CheckSyntax(shellName, module.Path)
+		if errors.Is(err, exec.ErrNotFound) {
+			result.Status = checkWarn
+			result.Message = fmt.Sprintf("%s not found, syntax not checked", checker)
+			result.Details = nil
+			return result
+		}
+		if err != nil {
+			result.Status = checkFail
+			result.Details = append(result.Details, fmt.Sprintf("%s: %s", module.RelPath(), err))
+		}
+	}
+
+	switch result.Status {
+	case checkFail:
+		result.Message = fmt.Sprintf("some modules fail '%s -n'", checker)
+	default:
+		result.Message = fmt.Sprintf("%d module(s) pass '%s -n'", len(modules), checker)
+	}
+	return result
+}
+
+// checkLoadOrder resolves the after= directives of the modules and lists
+// them in the order the loader sources them.
+func checkLoadOrder() checkResult {
+	result := checkResult{Name: "load order"}
+
+	order, err := shell.LoadOrder()
+	if err == nil {
+		_, err = shell.LoaderScript(nil)
+	}
+	if err != nil {
+		result.Status = checkFail
+		result.Message = "cannot resolve the module load order"
+		result.Details = []string{err.Error()}
+		return result
+	}
+
+	result.Status = checkPass
+	directed := 0
+	for i, module := range order {
+		detail := fmt.Sprintf("%d. %s", i+1, module.RelPath())
+		if after, _ := module.After(); len(after) > 0 {
+			directed++
+			detail += " (after " + strings.Join(after, ", ") + ")"
+		}
+		if triggers, _ := module.LazyTriggers(); len(triggers) > 0 {
+			detail += " (lazy: " + strings.Join(triggers, ", ") + ")"
+		}
+		result.Details = append(result.Details, detail)
+	}
+	result.Message = fmt.Sprintf("%d module(s), %d with after= directives", len(order), directed)
+	return result
+}
+
+func checkBundle(shellName string) checkResult {
+	result := checkResult{Name: "bundle", Status: checkPass}
+
+	if !shell.BundleExists() {
+		result.Message = "not built, modules are sourced one by one"
+		return result
+	}
+
+	current, err := shell.BundleIsCurrent(shellName)
+	switch {
+	case err != nil:
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("cannot check %s: %v", shell.GetBundlePath(), err)
+	case !current:
+		result.Status = checkWarn
+		result.Message = fmt.Sprintf("%s is out of date, run 'dotwaifu build'", shell.GetBundlePath())
+	default:
+		result.Message = fmt.Sprintf("%s is up to date", shell.GetBundlePath())
+	}
+	return result
+}
+
+// doctorProfileTimeout bounds the startup check, so that a module that hangs
+// fails the check instead of hanging doctor.
+const doctorProfileTimeout = 10 * time.Second
+
+// checkStartup profiles the modules and warns about those slower than the
+// configured threshold. Deferred modules do not count.
+func checkStartup(cfg *config.Config, shellName string) checkResult {
+	result := checkResult{Name: "startup", Status: checkPass}
+
+	profile, err := shell.ProfileModules(shellName, doctorProfileTimeout)
+	var timeout *shell.ProfileTimeoutError
+	switch {
+	case errors.As(err, &timeout):
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("%s does not finish loading, see 'dotwaifu bisect'", timeout.Module)
+		result.Details = []string{err.Error()}
+		return result
+	case err != nil:
+		result.Status = checkWarn
+		result.Message = fmt.Sprintf("cannot profile modules: %v", err)
+		return result
+	}
+
+	threshold := cfg.ProfileThreshold()
+	for _, timing := range profile.Modules {
+		if !timing.Deferred && timing.Duration > threshold {
+			result.Details = append(result.Details, fmt.Sprintf("%s: %.1fms", timing.Module.RelPath(), milliseconds(timing.Duration)))
+		}
+	}
+
+	if len(result.Details) > 0 {
+		result.Status = checkWarn
+		result.Message = fmt.Sprintf("%d module(s) slower than %.0fms, see 'dotwaifu profile'", len(result.Details), milliseconds(threshold))
+		return result
+	}
+
+	result.Message = fmt.Sprintf("modules load in %.1fms, none slower than %.0fms", milliseconds(profile.Total()), milliseconds(threshold))
+	return result
+}
+
+// checkLastStart reports the modules that failed to load on the last shell
+// start. Failures of modules edited since then only warn, they may be fixed.
+func checkLastStart() checkResult {
+	result := checkResult{Name: "last start", Status: checkPass}
+
+	status, err := shell.ReadStatus()
+	switch {
+	case err != nil:
+		result.Status = checkWarn
+		result.Message = fmt.Sprintf("cannot read %s: %v", shell.GetStatusPath(), err)
+		return result
+	case status == nil:
+		result.Message = "no shell start recorded yet"
+		return result
+	case len(status.Failures) == 0:
+		result.Message = fmt.Sprintf("all modules loaded at %s", status.Time.Format("2006-01-02 15:04"))
+		return result
+	}
+
+	result.Status = checkWarn
+	for _, failure := range status.Failures {
+		detail := fmt.Sprintf("%s: %s", failure.Module, failure.Reason)
+		if status.ChangedSince(failure) {
+			detail += " (changed since)"
+		} else {
+			result.Status = checkFail
+		}
+		result.Details = append(result.Details, detail)
+	}
+	result.Message = fmt.Sprintf("%d module(s) failed to load at %s, see 'dotwaifu status'", len(status.Failures), status.Time.Format("2006-01-02 15:04"))
+	return result
+}
+
+func checkProjects() checkResult {
+	result := checkResult{Name: "projects", Status: checkPass}
+
+	projects, err := shell.ListProjects()
+	if err != nil {
+		result.Status = checkFail
+		result.Message = fmt.Sprintf("cannot list projects: %v", err)
+		return result
+	}
+
+	activated, disabled := 0, 0
+	for _, project := range projects {
+		manifest, err := shell.LoadProjectManifest(project)
+		if err != nil {
+			result.Status = checkFail
+			result.Details = append(result.Details, fmt.Sprintf("%s: %v", shell.GetProjectManifestPath(project), err))
+			continue
+		}
+		switch {
+		case manifest.Disabled:
+			disabled++
+		case manifest.Activation.IsSet():
+			activated++
+		}
+	}
+
+	if result.Status == checkFail {
+		result.Message = "some project manifests do not parse"
+		return result
+	}
+
+	result.Message = fmt.Sprintf("%d project(s), %d activated by directory, %d disabled", len(projects), activated, disabled)
+	return result
+}
+
+func checkBackups(shellName string) checkResult {
+	result := checkResult{Name: "backups", Status: checkPass}
+
+	var kept string
+	for _, candidate := range []string{"zsh", "bash", "fish", "nu", "pwsh"} {
+		backupPath := shell.GetBackupPath(candidate)
+		if !fileExists(backupPath) {
+			continue
+		}
+
+		// The backup of the active RC file is what uninstall restores
+		if candidate == shellName && shell.HasDotwaifuIntegration(candidate) {
+			kept = backupPath
+			continue
+		}
+		result.Details = append(result.Details, backupPath)
+	}
+
+	switch {
+	case len(result.Details) > 0:
+		result.Status = checkWarn
+		result.Message = fmt.Sprintf("%d leftover backup(s) from a previous install", len(result.Details))
+	case kept != "":
+		result.Message = fmt.Sprintf("only %s, kept for 'dotwaifu uninstall'", kept)
+	default:
+		result.Message = "no leftover backups"
+	}
+	return result
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/shell"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// doctorChecks runs the doctor checks and returns them by name.
+func doctorChecks() map[string]checkResult {
+	checks := make(map[string]checkResult)
+	for _, check := range runDoctorChecks() {
+		checks[check.Name] = check
+	}
+	return checks
+}
+
+func TestDoctorChecks(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	newMachine(t, map[string]string{
+		"core/env.sh":     "export EDITOR=\"vim\"\n",
+		"core/scripts.sh": "if true; then\n",
+	})
+
+	checks := doctorChecks()
+	if got := checks["integration"].Status; got != checkFail {
+		t.Errorf("integration without an RC file = %s", got)
+	}
+	if got := checks["config"].Status; got != checkPass {
+		t.Errorf("config = %s: %s", got, checks["config"].Message)
+	}
+	if got := checks["repository"].Status; got != checkWarn {
+		t.Errorf("repository before the first sync = %s", got)
+	}
+
+	modules := checks["modules"]
+	if modules.Status != checkFail || len(modules.Details) != 1 || !strings.HasPrefix(modules.Details[0], "core/scripts.sh: ") {
+		t.Errorf("modules = %s %v", modules.Status, modules.Details)
+	}
+
+	if err := shell.CreateNewRC("bash"); err != nil {
+		t.Fatal(err)
+	}
+	writeModule(t, "core/scripts.sh", "if true; then :; fi\n")
+
+	checks = doctorChecks()
+	for _, name := range []string{"integration", "modules", "backups"} {
+		if got := checks[name].Status; got != checkPass {
+			t.Errorf("%s = %s: %s %v", name, got, checks[name].Message, checks[name].Details)
+		}
+	}
+}
+
+func TestDoctorBackups(t *testing.T) {
+	newMachine(t, nil)
+	for _, name := range []string{"bash", "zsh"} {
+		if err := os.WriteFile(shell.GetBackupPath(name), []byte("alias g='git'\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(shell.GetRCFilePath("bash"), []byte("alias g='git'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := shell.AppendToExistingRC("bash"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The backup of the active RC file is what uninstall restores
+	result := checkBackups("bash")
+	if result.Status != checkWarn || len(result.Details) != 1 || result.Details[0] != shell.GetBackupPath("zsh") {
+		t.Errorf("checkBackups = %s %v", result.Status, result.Details)
+	}
+}
+
+func TestDoctorStartup(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	newMachine(t, map[string]string{
+		"core/env.sh":     "export EDITOR=vim\n",
+		"core/scripts.sh": "sleep 0.1\n",
+	})
+
+	cfg := &config.Config{ProfileThresholdMs: 50}
+	if result := checkStartup(cfg, "bash"); result.Status != checkWarn || len(result.Details) != 1 || !strings.HasPrefix(result.Details[0], "core/scripts.sh: ") {
+		t.Errorf("checkStartup = %s %v", result.Status, result.Details)
+	}
+
+	cfg.ProfileThresholdMs = 5000
+	if result := checkStartup(cfg, "bash"); result.Status != checkPass {
+		t.Errorf("checkStartup = %s %v", result.Status, result.Details)
+	}
+}
@@ -1,12 +1,16 @@
 package cmd
 
 import (
+	"bytes"
 	"dotwaifu/internal/config"
 	"dotwaifu/internal/shell"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
@@ -17,6 +21,14 @@ var editCmd = &cobra.Command{
 	Short: "Edit configuration files",
 	Long: `Edit dotwaifu configuration files with interactive menus or direct access.
 
+The editor works on a copy of the module. When you close it, the copy is
+checked with bash -n / zsh -n and 'dotwaifu lint', and only replaces the
+module once it parses and has no lint errors. Otherwise you can re-open the
+editor, discard your changes or save anyway.
+
+GUI editors such as code and subl are started with their wait flag, so that
+dotwaifu knows when you close the file.
+
 Examples:
   dotwaifu edit                    # Interactive menu for config type
   dotwaifu edit -p flutter         # Interactive menu for flutter project
@@ -43,7 +55,8 @@ func runEdit(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	configTypes := []string{"paths", "aliases", "env", "scripts"}
+	types := cfg.ConfigTypes()
+	configTypes := cfg.TypeNames()
 
 	var configType, projectName string
 
@@ -63,6 +76,9 @@ func runEdit(cmd *cobra.Command, args []string) {
 		prompt := &survey.Select{
 			Message: "Which configuration would you like to edit?",
 			Options: configTypes,
+			Description: func(value string, index int) string {
+				return types[index].Description
+			},
 		}
 		survey.AskOne(prompt, &configType)
 	}
@@ -93,27 +109,214 @@ func runEdit(cmd *cobra.Command, args []string) {
 
 		if _, err := os.Stat(coreDir); os.IsNotExist(err) {
 			fmt.Println("Shell structure not found. Creating basic structure...")
-			if err := shell.CreateBasicStructure(); err != nil {
-				fmt.Printf("Error creating shell structure: %v\n", err)
-				return
-			}
+		}
+
+		// Also creates core modules for types added to config.yaml since
+		if err := shell.CreateBasicStructure(); err != nil {
+			fmt.Printf("Error creating shell structure: %v\n", err)
+			return
 		}
 	}
 
 	fmt.Printf("Opening %s...\n", filePath)
-	editorCmd := exec.Command(cfg.PreferredEditor, filePath)
-	editorCmd.Stdin = os.Stdin
-	editorCmd.Stdout = os.Stdout
-	editorCmd.Stderr = os.Stderr
+	module := shell.Module{Project: projectName, Type: configType, Path: filePath}
+	changed, err := editModule(cfg, module)
+	if err != nil {
+		fmt.Printf("Error editing %s: %v\n", module.RelPath(), err)
+		return
+	}
+	if !changed {
+		return
+	}
 
-	if err := editorCmd.Run(); err != nil {
-		fmt.Printf("Error opening editor: %v\n", err)
+	if err := refreshTranslations(cfg.DetectedShell); err != nil {
+		fmt.Printf("Error translating modules for %s: %v\n", cfg.DetectedShell, err)
 		return
 	}
 
 	fmt.Println("\nTo apply your changes:")
 	fmt.Printf("• Run 'dotwaifu reload' to reload configuration\n")
-	fmt.Printf("• Or manually: 'source ~/.bashrc' (or ~/.zshrc)\n")
+	fmt.Printf("• Or manually: 'source %s'\n", shell.GetRCFilePath(cfg.DetectedShell))
+}
+
+const (
+	editReopen  = "Re-open the editor"
+	editDiscard = "Discard my changes"
+	editForce   = "Save anyway"
+)
+
+// editModule opens a copy of module in the editor and moves it into place
+// only once it passes the syntax check and has no lint errors, or the user
+// saves it anyway. It reports whether the module changed.
+func editModule(cfg *config.Config, module shell.Module) (bool, error) {
+	info, err := os.Stat(module.Path)
+	if err != nil {
+		return false, err
+	}
+	original, err := os.ReadFile(module.Path)
+	if err != nil {
+		return false, err
+	}
+
+	// The copy lives in the state directory so that the loader does not pick
+	// it up and renaming it over the module is atomic
+	editDir := filepath.Join(config.GetStateDir(), "edit")
+	if err := os.MkdirAll(editDir, 0755); err != nil {
+		return false, err
+	}
+	copyFile, err := os.CreateTemp(editDir, module.Type+"-*.sh")
+	if err != nil {
+		return false, err
+	}
+	copyPath := copyFile.Name()
+	_, err = copyFile.Write(original)
+	if closeErr := copyFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(copyPath)
+		return false, err
+	}
+
+	keep := false
+	defer func() {
+		if !keep {
+			os.Remove(copyPath)
+		}
+	}()
+
+	for {
+		opened := time.Now()
+		if err := openInEditor(cfg.PreferredEditor, copyPath); err != nil {
+			return false, err
+		}
+
+		edited, err := os.ReadFile(copyPath)
+		if err != nil {
+			return false, err
+		}
+		if bytes.Equal(edited, original) && time.Since(opened) < time.Second {
+			// A GUI editor we don't know the wait flag of may still have the
+			// copy open
+			fmt.Printf("The editor returned right away, it may still have %s open.\n", copyPath)
+			var done string
+			prompt := &survey.Input{Message: "Press Enter once you have saved and closed it"}
+			if err := survey.AskOne(prompt, &done); err == nil {
+				if edited, err = os.ReadFile(copyPath); err != nil {
+					return false, err
+				}
+			}
+		}
+		if bytes.Equal(edited, original) {
+			fmt.Println("No changes.")
+			return false, nil
+		}
+
+		problems := validateModule(cfg, module, copyPath, string(edited))
+		if len(problems) == 0 {
+			break
+		}
+
+		fmt.Printf("\n%s has problems:\n", module.RelPath())
+		for _, problem := range problems {
+			fmt.Printf("  ✗ %s\n", problem)
+		}
+		fmt.Println()
+
+		var action string
+		prompt := &survey.Select{
+			Message: "What do you want to do?",
+			Options: []string{editReopen, editDiscard, editForce},
+		}
+		if err := survey.AskOne(prompt, &action); err != nil {
+			keep = true
+			fmt.Printf("Your edits are kept in %s\n", copyPath)
+			return false, err
+		}
+
+		switch action {
+		case editDiscard:
+			fmt.Printf("Discarded your changes to %s\n", module.RelPath())
+			return false, nil
+		case editForce:
+			return true, saveModuleCopy(copyPath, module, info.Mode())
+		}
+	}
+
+	return true, saveModuleCopy(copyPath, module, info.Mode())
+}
+
+// validateModule runs '<shell> -n' and the lint checks on the edited copy
+// of module and describes the problems that stop it from being saved. Lint
+// issues below error severity are only printed.
+func validateModule(cfg *config.Config, module shell.Module, copyPath, content string) []string {
+	var problems []string
+	checker := shell.ModuleShell(cfg.DetectedShell)
+
+	err := shell.CheckSyntax(cfg.DetectedShell, copyPath)
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		fmt.Printf("Note: %s not found, syntax not checked\n", checker)
+	case err != nil:
+		problems = append(problems, fmt.Sprintf("%s -n: %v", checker, err))
+	}
+
+	issues, err := shell.LintModule(cfg, module, content)
+	if err != nil {
+		fmt.Printf("Note: lint checks skipped: %v\n", err)
+	}
+	for _, issue := range issues {
+		if issue.Severity == config.SeverityError {
+			problems = append(problems, issue.String())
+		} else {
+			fmt.Printf("⚠ %s\n", issue)
+		}
+	}
+	return problems
+}
+
+func saveModuleCopy(copyPath string, module shell.Module, mode os.FileMode) error {
+	if err := os.Chmod(copyPath, mode.Perm()); err != nil {
+		return err
+	}
+	if err := os.Rename(copyPath, module.Path); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Saved %s\n", module.RelPath())
+	return nil
+}
+
+// editorWaitFlags make GUI editors block until the file is closed, instead
+// of handing it to a running window and returning at once.
+var editorWaitFlags = map[string]string{
+	"code":          "--wait",
+	"code-insiders": "--wait",
+	"codium":        "--wait",
+	"cursor":        "--wait",
+	"subl":          "--wait",
+	"zed":           "--wait",
+	"atom":          "--wait",
+	"mate":          "--wait",
+	"gvim":          "--nofork",
+	"mvim":          "--nofork",
+	"kate":          "--block",
+}
+
+func openInEditor(editor, filePath string) error {
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		return fmt.Errorf("no editor configured")
+	}
+	if flag, ok := editorWaitFlags[filepath.Base(args[0])]; ok && !contains(args[1:], flag) {
+		args = append(args, flag)
+	}
+
+	editorCmd := exec.Command(args[0], append(args[1:], filePath)...)
+	editorCmd.Stdin = os.Stdin
+	editorCmd.Stdout = os.Stdout
+	editorCmd.Stderr = os.Stderr
+
+	return editorCmd.Run()
 }
 
 func contains(slice []string, item string) bool {
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/shell"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeEditor returns an editor that replaces the file it opens with content.
+func fakeEditor(t *testing.T, content string) string {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "content"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	editor := filepath.Join(dir, "editor")
+	script := "#!/bin/sh\ncat '" + filepath.Join(dir, "content") + "' > \"$1\"\n"
+	if err := os.WriteFile(editor, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return editor
+}
+
+func TestEditModule(t *testing.T) {
+	newMachine(t, map[string]string{"core/aliases.sh": "alias g=git\n"})
+	path := filepath.Join(shell.GetCoreDir(), "aliases.sh")
+	if err := os.Chmod(path, 0600); err != nil {
+		t.Fatal(err)
+	}
+	module := shell.Module{Type: shell.CategoryAliases, Path: path}
+
+	cfg := &config.Config{DetectedShell: "bash", PreferredEditor: fakeEditor(t, "alias g=git\n")}
+	if changed, err := editModule(cfg, module); err != nil || changed {
+		t.Errorf("editModule without changes = %v, %v", changed, err)
+	}
+
+	cfg.PreferredEditor = fakeEditor(t, "alias g=git\nalias k=kubectl\n")
+	if changed, err := editModule(cfg, module); err != nil || !changed {
+		t.Errorf("editModule = %v, %v", changed, err)
+	}
+	if got := readModule(t, "core/aliases.sh"); got != "alias g=git\nalias k=kubectl\n" {
+		t.Errorf("aliases.sh after editing = %q", got)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("mode after editing = %v, %v", info.Mode(), err)
+	}
+
+	if copies, _ := filepath.Glob(filepath.Join(config.GetStateDir(), "edit", "*")); len(copies) > 0 {
+		t.Errorf("edited copies left behind: %v", copies)
+	}
+}
+
+func TestValidateModule(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	newMachine(t, nil)
+	module := shell.Module{Type: shell.CategoryAliases, Path: filepath.Join(shell.GetCoreDir(), "aliases.sh")}
+	copyPath := filepath.Join(t.TempDir(), "aliases.sh")
+
+	content := "alias g=git\nalias g=git\nif true; then\n"
+	if err := os.WriteFile(copyPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Lint warnings are shown but do not stop the module from being saved
+	cfg := &config.Config{DetectedShell: "bash"}
+	problems := validateModule(cfg, module, copyPath, content)
+	if len(problems) != 1 || !strings.HasPrefix(problems[0], "bash -n: ") {
+		t.Errorf("validateModule = %q", problems)
+	}
+
+	cfg.Lint = map[string]string{"duplicate-alias": config.SeverityError}
+	problems = validateModule(cfg, module, copyPath, content)
+	if len(problems) != 2 || !strings.HasSuffix(problems[1], "[duplicate-alias]") {
+		t.Errorf("validateModule with duplicate-alias as an error = %q", problems)
+	}
+}
+
+func TestOpenInEditorWaitsForGUIEditors(t *testing.T) {
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$*\" > '" + argsFile + "'\n"
+	if err := os.WriteFile(filepath.Join(dir, "code"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	tests := map[string]string{
+		"code":           "--wait /tmp/aliases.sh\n",
+		"code -n":        "-n --wait /tmp/aliases.sh\n",
+		"code --wait":    "--wait /tmp/aliases.sh\n",
+		dir + "/code -r": "-r --wait /tmp/aliases.sh\n",
+	}
+	for editor, want := range tests {
+		if err := openInEditor(editor, "/tmp/aliases.sh"); err != nil {
+			t.Fatalf("openInEditor(%q): %v", editor, err)
+		}
+		if got, _ := os.ReadFile(argsFile); string(got) != want {
+			t.Errorf("openInEditor(%q) ran code with %q, want %q", editor, got, want)
+		}
+	}
+}
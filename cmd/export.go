@@ -29,35 +29,38 @@ func runExport(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	configDir := config.GetConfigDir()
 	exportContent := fmt.Sprintf("%s\n# Exported dotwaifu configuration\n\n", shell.GetShellComment(cfg.DetectedShell))
 
-	coreDir := filepath.Join(configDir, "shell", "shared", "core")
-	coreFiles := []string{"paths.sh", "aliases.sh", "env.sh", "scripts.sh"}
+	modules, err := shell.LoadOrder()
+	if err != nil {
+		fmt.Printf("Error listing modules: %v\n", err)
+		return
+	}
 
-	for _, file := range coreFiles {
-		filePath := filepath.Join(coreDir, file)
-		if content, err := os.ReadFile(filePath); err == nil {
-			exportContent += fmt.Sprintf("# === %s ===\n%s\n\n", file, string(content))
+	project := ""
+	for _, module := range modules {
+		content, err := os.ReadFile(module.Path)
+		if err != nil {
+			continue
 		}
-	}
+		translated, _ := shell.TranslateModule(cfg.DetectedShell, string(content))
 
-	projectsDir := filepath.Join(configDir, "shell", "shared", "projects")
-	if entries, err := os.ReadDir(projectsDir); err == nil {
-		for _, entry := range entries {
-			if entry.IsDir() {
-				projectDir := filepath.Join(projectsDir, entry.Name())
-				exportContent += fmt.Sprintf("# === %s project ===\n", entry.Name())
+		if module.Project == "" {
+			exportContent += fmt.Sprintf("# === %s.sh ===\n%s\n\n", module.Type, translated)
+			continue
+		}
 
-				for _, file := range coreFiles {
-					filePath := filepath.Join(projectDir, file)
-					if content, err := os.ReadFile(filePath); err == nil {
-						exportContent += fmt.Sprintf("# %s\n%s\n", file, string(content))
-					}
-				}
+		if module.Project != project {
+			if project != "" {
 				exportContent += "\n"
 			}
+			project = module.Project
+			exportContent += fmt.Sprintf("# === %s project ===\n", project)
 		}
+		exportContent += fmt.Sprintf("# %s.sh\n%s\n", module.Type, translated)
+	}
+	if project != "" {
+		exportContent += "\n"
 	}
 
 	home, _ := os.UserHomeDir()
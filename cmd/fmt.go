@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/diff"
+	"dotwaifu/internal/shell"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	fmtCheck bool
+	fmtDiff  bool
+	fmtSort  bool
+)
+
+var fmtCmd = &cobra.Command{
+	Use:   "fmt",
+	Short: "Rewrite simple modules in a canonical form",
+	Long: `Rewrite every module made only of aliases, variables, PATH changes and
+comments in one canonical form, so that the shared repository doesn't get
+noisy diffs:
+
+  alias ll='ls -la'
+  export EDITOR="nvim"
+  export PATH="$HOME/bin:$PATH"
+
+Values keep their meaning: quoting is only changed where it makes no
+difference. Exact duplicates of an earlier line are dropped, and runs of blank
+lines become one. With --sort, or fmt_sort: true in config.yaml, aliases and
+variables are also sorted by name within each block between comments and blank
+lines. Modules with other code are left alone.
+
+--check changes nothing and exits with a non-zero status if any module needs
+formatting. To run it on every 'dotwaifu sync', add it to config.yaml:
+
+  pre_commit:
+    - dotwaifu fmt --check
+
+Examples:
+  dotwaifu fmt
+  dotwaifu fmt --sort
+  dotwaifu fmt --check --diff`,
+	Args: cobra.NoArgs,
+	Run:  runFmt,
+}
+
+func init() {
+	fmtCmd.Flags().BoolVar(&fmtCheck, "check", false, "Only report modules that need formatting")
+	fmtCmd.Flags().BoolVar(&fmtDiff, "diff", false, "Print the changes instead of writing them")
+	fmtCmd.Flags().BoolVar(&fmtSort, "sort", false, "Sort aliases and variables within each block (default from config.yaml)")
+}
+
+func runFmt(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	modules, err := shell.ListModules()
+	if err != nil {
+		fmt.Printf("Error listing modules: %v\n", err)
+		os.Exit(1)
+	}
+
+	changed := 0
+	for _, module := range modules {
+		if module.HasConflict() {
+			continue
+		}
+
+		content, err := os.ReadFile(module.Path)
+		if err != nil {
+			fmt.Printf("Error reading %s: %v\n", module.RelPath(), err)
+			os.Exit(1)
+		}
+
+		formatted, err := shell.FormatModule(string(content), fmtSort || cfg.FmtSort)
+		var notSimple *shell.NotSimpleError
+		if errors.As(err, &notSimple) {
+			// Scripts are expected to have code, the others are worth a note
+			switch module.Type {
+			case shell.CategoryAliases, shell.CategoryEnv, shell.CategoryPaths:
+				fmt.Printf("Note: %s left alone, %v\n", module.RelPath(), err)
+			}
+			continue
+		}
+		if formatted == string(content) {
+			continue
+		}
+		changed++
+
+		switch {
+		case fmtDiff:
+			fmt.Print(diff.Unified(string(content), formatted, module.RelPath(), module.RelPath()+" (formatted)"))
+		case fmtCheck:
+			fmt.Printf("%s needs formatting\n", module.RelPath())
+		default:
+			info, err := os.Stat(module.Path)
+			if err == nil {
+				err = os.WriteFile(module.Path, []byte(formatted), info.Mode())
+			}
+			if err != nil {
+				fmt.Printf("Error writing %s: %v\n", module.RelPath(), err)
+				os.Exit(1)
+			}
+			fmt.Printf("✓ Formatted %s\n", module.RelPath())
+		}
+	}
+
+	switch {
+	case changed == 0:
+		fmt.Println("✅ All modules are formatted")
+	case fmtCheck || fmtDiff:
+		if fmtCheck {
+			fmt.Printf("\n%d module(s) need formatting, run 'dotwaifu fmt'\n", changed)
+			os.Exit(1)
+		}
+	default:
+		if err := refreshTranslations(cfg.DetectedShell); err != nil {
+			fmt.Printf("Error translating modules for %s: %v\n", cfg.DetectedShell, err)
+			os.Exit(1)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import "testing"
+
+func TestFmt(t *testing.T) {
+	newMachine(t, map[string]string{
+		"core/aliases.sh": "alias ll=\"ls -la\"\nalias g=git\nalias g=git\n",
+		"core/env.sh":     "export EDITOR=\"nvim\"\n",
+		"core/scripts.sh": "mkcd() { mkdir -p \"$1\" && cd \"$1\"; }\nalias m=mkcd\n",
+	})
+
+	setFlag(t, &fmtSort, true)
+	runFmt(nil, nil)
+
+	if got := readModule(t, "core/aliases.sh"); got != "alias g='git'\nalias ll='ls -la'\n" {
+		t.Errorf("aliases.sh after fmt = %q", got)
+	}
+	if got := readModule(t, "core/scripts.sh"); got != "mkcd() { mkdir -p \"$1\" && cd \"$1\"; }\nalias m=mkcd\n" {
+		t.Errorf("scripts.sh after fmt = %q", got)
+	}
+}
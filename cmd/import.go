@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/shell"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+)
+
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import an existing RC file into organized modules",
+	Long: `Split a monolithic shell RC file into dotwaifu modules.
+
+Every statement is sorted into core/paths.sh, aliases.sh, env.sh or scripts.sh
+(PATH changes, aliases, other exports, and functions or other logic). Anything
+that doesn't fit goes to misc.sh. You can review the result before anything is
+written, and optionally remove the imported lines from the RC file.
+
+Examples:
+  dotwaifu import                  # Import your shell's RC file
+  dotwaifu import ~/.bash_profile  # Import a specific file`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runImport,
+}
+
+const (
+	importWriteAll = "Import everything as shown"
+	importReview   = "Review each statement"
+	importCancel   = "Cancel"
+	importSkip     = "skip (leave it in the RC file)"
+)
+
+func runImport(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+
+	shellName := cfg.DetectedShell
+	if shellName == "" {
+		shellName = shell.DetectShell()
+	}
+
+	rcPath := shell.GetRCFilePath(shellName)
+	if len(args) > 0 {
+		rcPath = expandHome(args[0])
+	}
+
+	content, err := os.ReadFile(rcPath)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", rcPath, err)
+		return
+	}
+
+	items := shell.ClassifyRC(string(content))
+	if len(items) == 0 {
+		fmt.Printf("Nothing to import from %s.\n", rcPath)
+		return
+	}
+
+	printImportPreview(cfg, items)
+
+	var action string
+	prompt := &survey.Select{
+		Message: fmt.Sprintf("Import %d statement(s) from %s?", len(items), rcPath),
+		Options: []string{importWriteAll, importReview, importCancel},
+	}
+	if err := survey.AskOne(prompt, &action); err != nil || action == importCancel {
+		fmt.Println("Import cancelled.")
+		return
+	}
+
+	if action == importReview {
+		if err := reviewImportItems(cfg, items); err != nil {
+			fmt.Println("Import cancelled.")
+			return
+		}
+	}
+
+	imported, err := writeImportedModules(cfg, rcPath, items)
+	if err != nil {
+		fmt.Printf("Error writing %v\n", err)
+		return
+	}
+	if len(imported) == 0 {
+		fmt.Println("Nothing selected for import.")
+		return
+	}
+
+	var strip bool
+	stripPrompt := &survey.Confirm{
+		Message: fmt.Sprintf("Remove the imported lines from %s? (a backup is kept at %s_backup)", rcPath, rcPath),
+		Default: true,
+	}
+	if err := survey.AskOne(stripPrompt, &strip); err != nil {
+		strip = false
+	}
+
+	if strip {
+		if err := stripRCFile(rcPath, string(content), imported); err != nil {
+			fmt.Printf("Error updating %s: %v\n", rcPath, err)
+			return
+		}
+		fmt.Printf("✓ Removed imported lines from %s\n", rcPath)
+	}
+
+	if rcPath == shell.GetRCFilePath(shellName) && !shell.HasDotwaifuIntegration(shellName) {
+		fmt.Println("\nYour RC file doesn't load dotwaifu yet. Run 'dotwaifu init' to add the loader.")
+		return
+	}
+
+	fmt.Println("\nRun 'dotwaifu reload' to apply the imported configuration.")
+}
+
+// groupImportItems sorts the items that are not skipped by the module they
+// go to. The modules are in the order of moduleChoices, followed by any other
+// module a statement was assigned to.
+func groupImportItems(cfg *config.Config, items []shell.ImportItem) ([]string, map[string][]shell.ImportItem) {
+	choices := moduleChoices(cfg)
+	grouped := make(map[string][]shell.ImportItem)
+	var extra []string
+	for _, item := range items {
+		if item.Category == importSkip {
+			continue
+		}
+		if len(grouped[item.Category]) == 0 && !contains(choices, item.Category) {
+			extra = append(extra, item.Category)
+		}
+		grouped[item.Category] = append(grouped[item.Category], item)
+	}
+
+	var modules []string
+	for _, name := range append(choices, extra...) {
+		if len(grouped[name]) > 0 {
+			modules = append(modules, name)
+		}
+	}
+	return modules, grouped
+}
+
+func printImportPreview(cfg *config.Config, items []shell.ImportItem) {
+	modules, grouped := groupImportItems(cfg, items)
+	for _, name := range modules {
+		fmt.Printf("\ncore/%s.sh (%d statement(s)):\n", name, len(grouped[name]))
+		for _, item := range grouped[name] {
+			for _, line := range item.Lines {
+				fmt.Printf("  %4d │ %s\n", item.StartLine, line)
+				item.StartLine++
+			}
+		}
+	}
+	fmt.Println()
+}
+
+// writeImportedModules appends the items that are not skipped to their core
+// modules and returns them, so that exactly these can be stripped from the
+// RC file.
+func writeImportedModules(cfg *config.Config, rcPath string, items []shell.ImportItem) ([]shell.ImportItem, error) {
+	modules, grouped := groupImportItems(cfg, items)
+
+	var imported []shell.ImportItem
+	for _, name := range modules {
+		var statements []string
+		for _, item := range grouped[name] {
+			statements = append(statements, item.Content())
+		}
+
+		moduleContent := fmt.Sprintf("\n# Imported from %s\n%s", rcPath, strings.Join(statements, "\n"))
+		if err := shell.AppendToModule("", name, moduleContent); err != nil {
+			return imported, fmt.Errorf("%s.sh: %w", name, err)
+		}
+		fmt.Printf("✓ Added %d statement(s) to core/%s.sh\n", len(statements), name)
+		imported = append(imported, grouped[name]...)
+	}
+	return imported, nil
+}
+
+// moduleChoices lists the modules a statement can be moved to: the config
+// types from config.yaml and the import categories.
+func moduleChoices(cfg *config.Config) []string {
+	choices := cfg.TypeNames()
+	for _, category := range shell.ImportCategories {
+		if !contains(choices, category) {
+			choices = append(choices, category)
+		}
+	}
+	return choices
+}
+
+func reviewImportItems(cfg *config.Config, items []shell.ImportItem) error {
+	options := append(moduleChoices(cfg), importSkip)
+
+	for i := range items {
+		fmt.Printf("\nLines %d-%d:\n", items[i].StartLine, items[i].EndLine)
+		for _, line := range items[i].Lines {
+			fmt.Printf("  │ %s\n", line)
+		}
+
+		prompt := &survey.Select{
+			Message: "Which module should this go to?",
+			Options: options,
+			Default: items[i].Category,
+		}
+		if err := survey.AskOne(prompt, &items[i].Category); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stripRCFile removes imported items from the RC file. The first backup is
+// never overwritten, so uninstall can still restore the original file.
+func stripRCFile(rcPath, content string, items []shell.ImportItem) error {
+	backupPath := rcPath + "_backup"
+	if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+		if err := os.WriteFile(backupPath, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	return os.WriteFile(rcPath, []byte(shell.StripItems(content, items)), 0644)
+}
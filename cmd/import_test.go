@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/shell"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStripRCFileKeepsFirstBackup(t *testing.T) {
+	rcPath := filepath.Join(t.TempDir(), ".bashrc")
+	original := "alias g='git'\nexport EDITOR=\"vim\"\n"
+	if err := os.WriteFile(rcPath, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	items := shell.ClassifyRC(original)
+	if err := stripRCFile(rcPath, original, items[:1]); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(rcPath)
+	if string(content) != "export EDITOR=\"vim\"\n" {
+		t.Errorf("RC after the first import = %q", content)
+	}
+
+	// A second import must not replace the backup of the original file
+	if err := stripRCFile(rcPath, string(content), shell.ClassifyRC(string(content))); err != nil {
+		t.Fatal(err)
+	}
+	content, _ = os.ReadFile(rcPath)
+	if string(content) != "" {
+		t.Errorf("RC after the second import = %q", content)
+	}
+	if backup, _ := os.ReadFile(rcPath + "_backup"); string(backup) != original {
+		t.Errorf("backup = %q, want %q", backup, original)
+	}
+}
+
+func TestImportIntoConfigTypes(t *testing.T) {
+	newMachine(t, nil)
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Types = []config.ConfigType{{Name: "completions", Order: 5}}
+
+	rcPath := filepath.Join(t.TempDir(), ".bashrc")
+	original := "alias g='git'\nsource <(kubectl completion bash)\nexport EDITOR=\"vim\"\n"
+	if err := os.WriteFile(rcPath, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	items := shell.ClassifyRC(original)
+	if len(items) != 3 {
+		t.Fatalf("ClassifyRC found %d items, want 3", len(items))
+	}
+	items[1].Category = "completions"
+	items[2].Category = importSkip
+
+	// The preview lists the modules in the order they are written
+	if modules, _ := groupImportItems(cfg, items); strings.Join(modules, " ") != "completions aliases" {
+		t.Errorf("modules = %v", modules)
+	}
+
+	imported, err := writeImportedModules(cfg, rcPath, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := stripRCFile(rcPath, original, imported); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := readModule(t, "core/completions.sh"); !strings.HasSuffix(got, "source <(kubectl completion bash)\n") {
+		t.Errorf("completions.sh = %q", got)
+	}
+	if got := readModule(t, "core/aliases.sh"); !strings.HasSuffix(got, "alias g='git'\n") {
+		t.Errorf("aliases.sh = %q", got)
+	}
+	if content, _ := os.ReadFile(rcPath); string(content) != "export EDITOR=\"vim\"\n" {
+		t.Errorf("RC after the import = %q", content)
+	}
+}
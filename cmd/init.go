@@ -16,6 +16,24 @@ var initCmd = &cobra.Command{
 	Run:   runInit,
 }
 
+// initAnswers are the choices made in the init wizard.
+type initAnswers struct {
+	Editor         string
+	InitBasic      bool
+	CreateExamples bool
+}
+
+// apply returns a copy of existing with the detected shell and the answers
+// filled in. Everything else in config.yaml is kept as it is.
+func (a initAnswers) apply(existing *config.Config, detectedShell string) *config.Config {
+	cfg := *existing
+	cfg.DetectedShell = detectedShell
+	cfg.PreferredEditor = a.Editor
+	cfg.InitBasic = a.InitBasic
+	cfg.CreateExamples = a.CreateExamples
+	return &cfg
+}
+
 func runInit(cmd *cobra.Command, args []string) {
 	fmt.Println("Welcome to dotwaifu!")
 	fmt.Println("dotwaifu organizes your shell configuration into separate, manageable files.")
@@ -44,12 +62,19 @@ func runInit(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	// An existing config.yaml provides the config types listed below and the
+	// settings init doesn't ask about
+	existing, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+
 	// Create organized config files with clear explanation
 	fmt.Println("\ndotwaifu creates separate files for different types of shell configuration:")
-	fmt.Println("  • paths.sh - for adding directories to your PATH")
-	fmt.Println("  • aliases.sh - for command shortcuts (like 'll' for 'ls -la')")
-	fmt.Println("  • env.sh - for environment variables")
-	fmt.Println("  • scripts.sh - for custom shell functions")
+	for _, t := range existing.ConfigTypes() {
+		fmt.Printf("  • %s.sh - %s\n", t.Name, t.Description)
+	}
 
 	var createConfigs bool
 	configPrompt := &survey.Confirm{
@@ -77,22 +102,12 @@ func runInit(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	answers := struct {
-		Editor         string
-		InitBasic      bool
-		CreateExamples bool
-	}{
+	answers := initAnswers{
 		Editor:         editor,
 		InitBasic:      createConfigs,
 		CreateExamples: createExamples,
 	}
-
-	cfg := &config.Config{
-		DetectedShell:   detectedShell,
-		PreferredEditor: answers.Editor,
-		InitBasic:       answers.InitBasic,
-		CreateExamples:  answers.CreateExamples,
-	}
+	cfg := answers.apply(existing, detectedShell)
 
 	if err := cfg.Save(); err != nil {
 		fmt.Printf("Error saving configuration: %v\n", err)
@@ -115,29 +130,9 @@ func runInit(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	hasExistingRC := shell.HasExistingRC(detectedShell)
-	if hasExistingRC {
-		if shell.HasDotwaifuIntegration(detectedShell) {
-			fmt.Printf("Your %s already has dotwaifu integration.\n", shell.GetRCFileName(detectedShell))
-		} else {
-			fmt.Printf("Backing up existing %s to %s_backup\n", shell.GetRCFileName(detectedShell), shell.GetRCFileName(detectedShell))
-			if err := shell.BackupExistingRC(detectedShell); err != nil {
-				fmt.Printf("Error creating backup: %v\n", err)
-				return
-			}
-
-			fmt.Printf("Adding dotwaifu loader to %s\n", shell.GetRCFileName(detectedShell))
-			if err := shell.AppendToExistingRC(detectedShell); err != nil {
-				fmt.Printf("Error adding integration: %v\n", err)
-				return
-			}
-		}
-	} else {
-		fmt.Printf("Creating new %s\n", shell.GetRCFileName(detectedShell))
-		if err := shell.CreateNewRC(detectedShell); err != nil {
-			fmt.Printf("Error creating RC file: %v\n", err)
-			return
-		}
+	if err := installIntegration(detectedShell); err != nil {
+		fmt.Printf("Error adding integration: %v\n", err)
+		return
 	}
 
 	fmt.Println("\nSetup complete!")
@@ -159,4 +154,28 @@ func runInit(cmd *cobra.Command, args []string) {
 		fmt.Printf("• 'source %s' (manual way)\n", shell.GetRCFilePath(detectedShell))
 		fmt.Printf("• or restart your terminal\n")
 	}
-}
\ No newline at end of file
+}
+
+func installIntegration(detectedShell string) error {
+	if err := refreshTranslations(detectedShell); err != nil {
+		return fmt.Errorf("translating modules: %w", err)
+	}
+
+	if shell.HasExistingRC(detectedShell) {
+		if shell.HasDotwaifuIntegration(detectedShell) {
+			fmt.Printf("Your %s already has dotwaifu integration.\n", shell.GetRCFileName(detectedShell))
+			return nil
+		}
+
+		fmt.Printf("Backing up existing %s to %s_backup\n", shell.GetRCFileName(detectedShell), shell.GetRCFileName(detectedShell))
+		if err := shell.BackupExistingRC(detectedShell); err != nil {
+			return fmt.Errorf("creating backup: %w", err)
+		}
+
+		fmt.Printf("Adding dotwaifu loader to %s\n", shell.GetRCFileName(detectedShell))
+		return shell.AppendToExistingRC(detectedShell)
+	}
+
+	fmt.Printf("Creating new %s\n", shell.GetRCFileName(detectedShell))
+	return shell.CreateNewRC(detectedShell)
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"reflect"
+	"testing"
+)
+
+func TestInitAnswersKeepOtherSettings(t *testing.T) {
+	existing := &config.Config{
+		DetectedShell:      "zsh",
+		PreferredEditor:    "vim",
+		Remote:             "https://github.com/me/dots.git",
+		Types:              []config.ConfigType{{Name: "completions", Order: 50}},
+		ProfileThresholdMs: 50,
+		Lint:               map[string]string{"missing-path": config.SeverityOff},
+		FmtSort:            true,
+		PreCommit:          []string{"dotwaifu fmt --check"},
+	}
+
+	answers := initAnswers{Editor: "nvim", InitBasic: true, CreateExamples: true}
+	cfg := answers.apply(existing, "bash")
+
+	want := *existing
+	want.DetectedShell = "bash"
+	want.PreferredEditor = "nvim"
+	want.InitBasic = true
+	want.CreateExamples = true
+	if !reflect.DeepEqual(*cfg, want) {
+		t.Errorf("apply = %+v, want %+v", *cfg, want)
+	}
+	if existing.PreferredEditor != "vim" {
+		t.Error("apply changed the existing config")
+	}
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/shell"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	lintJSON  bool
+	lintRules bool
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "Check the modules for dotwaifu-specific problems",
+	Long: `Check core and the enabled projects for problems shellcheck doesn't know
+about:
+
+  duplicate-alias       an alias is defined more than once, in core or projects
+  alias-shadows-binary  an alias hides a command of the same name on PATH
+  missing-path          a directory added to PATH does not exist
+  duplicate-path        a directory is added to PATH more than once
+  duplicate-env         a variable is set in more than one module
+  wrong-category        an alias, variable or PATH change is in the module of
+                        another type, e.g. an alias in env.sh
+
+Each rule reports at error, warning or info severity, or is turned off. Change
+them in config.yaml:
+
+  lint:
+    missing-path: off
+    alias-shadows-binary: error
+
+Exits with a non-zero status if any error is found. 'dotwaifu edit' does not
+save a module with errors unless you insist.
+
+Examples:
+  dotwaifu lint
+  dotwaifu lint --json
+  dotwaifu lint --rules`,
+	Args: cobra.NoArgs,
+	Run:  runLint,
+}
+
+type lintIssueReport struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Module   string `json:"module"`
+	Line     int    `json:"line"`
+	Message  string `json:"message"`
+}
+
+type lintReport struct {
+	Issues  []lintIssueReport `json:"issues"`
+	Summary map[string]int    `json:"summary"`
+}
+
+func init() {
+	lintCmd.Flags().BoolVar(&lintJSON, "json", false, "Print the issues as JSON")
+	lintCmd.Flags().BoolVar(&lintRules, "rules", false, "List the rules and their severities")
+}
+
+func runLint(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	var unknown []string
+	for rule := range cfg.Lint {
+		if _, ok := shell.LookupLintRule(rule); !ok {
+			unknown = append(unknown, rule)
+		}
+	}
+	sort.Strings(unknown)
+	for _, rule := range unknown {
+		fmt.Fprintf(os.Stderr, "Warning: config.yaml sets the severity of unknown lint rule %q\n", rule)
+	}
+
+	if lintRules {
+		for _, rule := range shell.LintRules {
+			fmt.Printf("%-22s %-8s %s\n", rule.Name, cfg.LintSeverity(rule.Name, rule.Severity), rule.Description)
+		}
+		return
+	}
+
+	issues, err := shell.Lint(cfg)
+	if err != nil {
+		fmt.Printf("Error linting modules: %v\n", err)
+		os.Exit(1)
+	}
+
+	report := lintReport{
+		Issues:  []lintIssueReport{},
+		Summary: map[string]int{config.SeverityError: 0, config.SeverityWarning: 0, config.SeverityInfo: 0},
+	}
+	for _, issue := range issues {
+		report.Issues = append(report.Issues, lintIssueReport{
+			Rule:     issue.Rule,
+			Severity: issue.Severity,
+			Module:   issue.Module,
+			Line:     issue.Line,
+			Message:  issue.Message,
+		})
+		report.Summary[issue.Severity]++
+	}
+
+	if lintJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Printf("Error encoding report: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+	} else if len(issues) == 0 {
+		fmt.Println("✅ No lint issues found")
+	} else {
+		for _, issue := range issues {
+			fmt.Println(issue)
+		}
+		fmt.Printf("\n%d error(s), %d warning(s), %d info\n",
+			report.Summary[config.SeverityError], report.Summary[config.SeverityWarning], report.Summary[config.SeverityInfo])
+	}
+
+	if report.Summary[config.SeverityError] > 0 {
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/shell"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	profileJSON      bool
+	profileThreshold int
+	profileTimeout   time.Duration
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Time how long each module takes to load",
+	Long: `Source every enabled module in load order in a non-interactive shell and
+report how long each module and each project took, slowest first.
+
+Modules that are lazy or belong to a project with activation rules are timed
+too, but marked as deferred and not counted in the startup total.
+
+Modules slower than profile_threshold_ms in config.yaml (default 100) are
+flagged here and reported by 'dotwaifu doctor'. A module that keeps the shell
+busy past --timeout is named as the one that hangs.
+
+Examples:
+  dotwaifu profile
+  dotwaifu profile --threshold 20
+  dotwaifu profile --json`,
+	Args: cobra.NoArgs,
+	Run:  runProfile,
+}
+
+type moduleTimingReport struct {
+	Module   string  `json:"module"`
+	Project  string  `json:"project"`
+	Ms       float64 `json:"ms"`
+	Deferred bool    `json:"deferred"`
+	Slow     bool    `json:"slow"`
+}
+
+type projectTimingReport struct {
+	Project  string  `json:"project"`
+	Ms       float64 `json:"ms"`
+	Deferred bool    `json:"deferred"`
+}
+
+type profileReport struct {
+	Shell       string                `json:"shell"`
+	ThresholdMs float64               `json:"threshold_ms"`
+	ShellMs     float64               `json:"shell_ms"`
+	TotalMs     float64               `json:"total_ms"`
+	Modules     []moduleTimingReport  `json:"modules"`
+	Projects    []projectTimingReport `json:"projects"`
+}
+
+func init() {
+	profileCmd.Flags().BoolVar(&profileJSON, "json", false, "Print the timings as JSON")
+	profileCmd.Flags().IntVar(&profileThreshold, "threshold", 0, "Flag modules slower than this many milliseconds (default from config.yaml)")
+	profileCmd.Flags().DurationVar(&profileTimeout, "timeout", 10*time.Second, "Give up when the modules take longer than this")
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// buildProfileReport sorts the timings slowest first and adds them up per
+// project; core modules count as project "core".
+func buildProfileReport(shellName string, profile *shell.Profile, threshold time.Duration) profileReport {
+	report := profileReport{
+		Shell:       shellName,
+		ThresholdMs: milliseconds(threshold),
+		ShellMs:     milliseconds(profile.Startup),
+		TotalMs:     milliseconds(profile.Total()),
+		Modules:     []moduleTimingReport{},
+		Projects:    []projectTimingReport{},
+	}
+
+	projects := make(map[string]int)
+	for _, timing := range profile.Modules {
+		project := timing.Module.Project
+		if project == "" {
+			project = "core"
+		}
+
+		report.Modules = append(report.Modules, moduleTimingReport{
+			Module:   timing.Module.RelPath(),
+			Project:  project,
+			Ms:       milliseconds(timing.Duration),
+			Deferred: timing.Deferred,
+			Slow:     timing.Duration > threshold,
+		})
+
+		i, ok := projects[project]
+		if !ok {
+			i = len(report.Projects)
+			projects[project] = i
+			report.Projects = append(report.Projects, projectTimingReport{Project: project, Deferred: true})
+		}
+		report.Projects[i].Ms += milliseconds(timing.Duration)
+		report.Projects[i].Deferred = report.Projects[i].Deferred && timing.Deferred
+	}
+
+	sort.SliceStable(report.Modules, func(i, j int) bool { return report.Modules[i].Ms > report.Modules[j].Ms })
+	sort.SliceStable(report.Projects, func(i, j int) bool { return report.Projects[i].Ms > report.Projects[j].Ms })
+	return report
+}
+
+func runProfile(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if cfg.DetectedShell == "" {
+		fmt.Println("No shell detected. Please run 'dotwaifu init' first.")
+		os.Exit(1)
+	}
+
+	threshold := cfg.ProfileThreshold()
+	if profileThreshold > 0 {
+		threshold = time.Duration(profileThreshold) * time.Millisecond
+	}
+
+	profile, err := shell.ProfileModules(cfg.DetectedShell, profileTimeout)
+	if err != nil {
+		fmt.Printf("Error profiling modules: %v\n", err)
+		os.Exit(1)
+	}
+
+	report := buildProfileReport(shell.ModuleShell(cfg.DetectedShell), profile, threshold)
+
+	if profileJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Printf("Error encoding report: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	if len(report.Modules) == 0 {
+		fmt.Println("No modules to profile.")
+		return
+	}
+
+	fmt.Printf("%-40s %10s\n", "Module", "Time")
+	for _, module := range report.Modules {
+		note := ""
+		switch {
+		case module.Deferred:
+			note = "  deferred"
+		case module.Slow:
+			note = fmt.Sprintf("  ⚠ over %.0fms", report.ThresholdMs)
+		}
+		fmt.Printf("%-40s %8.1fms%s\n", module.Module, module.Ms, note)
+	}
+
+	fmt.Printf("\n%-40s %10s\n", "Project", "Time")
+	for _, project := range report.Projects {
+		note := ""
+		if project.Deferred {
+			note = "  deferred"
+		}
+		fmt.Printf("%-40s %8.1fms%s\n", project.Project, project.Ms, note)
+	}
+
+	fmt.Printf("\nTotal: %.1fms for modules at startup, plus %.1fms for %s itself\n", report.TotalMs, report.ShellMs, report.Shell)
+}
@@ -0,0 +1,307 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/shell"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+)
+
+var projectDeleteYes bool
+
+var projectCmd = &cobra.Command{
+	Use:   "project",
+	Short: "List and manage projects",
+	Long: `Manage the projects under ~/.config/dotwaifu/shell/shared/projects.
+
+Examples:
+  dotwaifu project list
+  dotwaifu project rename flutter dart
+  dotwaifu project copy node deno
+  dotwaifu project disable python
+  dotwaifu project delete python`,
+}
+
+var projectListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List projects with their modules and line counts",
+	Args:  cobra.NoArgs,
+	Run:   runProjectList,
+}
+
+var projectRenameCmd = &cobra.Command{
+	Use:   "rename <project> <new-name>",
+	Short: "Rename a project",
+	Args:  cobra.ExactArgs(2),
+	Run:   runProjectRename,
+}
+
+var projectCopyCmd = &cobra.Command{
+	Use:   "copy <project> <new-name>",
+	Short: "Copy a project under a new name",
+	Args:  cobra.ExactArgs(2),
+	Run:   runProjectCopy,
+}
+
+var projectDeleteCmd = &cobra.Command{
+	Use:   "delete <project>",
+	Short: "Delete a project and its modules",
+	Args:  cobra.ExactArgs(1),
+	Run:   runProjectDelete,
+}
+
+var projectDisableCmd = &cobra.Command{
+	Use:   "disable <project>",
+	Short: "Stop loading a project without deleting it",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setProjectDisabled(args[0], true)
+	},
+}
+
+var projectEnableCmd = &cobra.Command{
+	Use:   "enable <project>",
+	Short: "Load a disabled project again",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		setProjectDisabled(args[0], false)
+	},
+}
+
+func init() {
+	projectDeleteCmd.Flags().BoolVarP(&projectDeleteYes, "yes", "y", false, "Delete without asking")
+
+	projectCmd.AddCommand(projectListCmd)
+	projectCmd.AddCommand(projectRenameCmd)
+	projectCmd.AddCommand(projectCopyCmd)
+	projectCmd.AddCommand(projectDeleteCmd)
+	projectCmd.AddCommand(projectDisableCmd)
+	projectCmd.AddCommand(projectEnableCmd)
+}
+
+func runProjectList(cmd *cobra.Command, args []string) {
+	projects, err := shell.ListProjects()
+	if err != nil {
+		fmt.Printf("Error listing projects: %v\n", err)
+		return
+	}
+
+	if len(projects) == 0 {
+		fmt.Println("No projects yet. Create one with 'dotwaifu edit <type> <project>'.")
+		return
+	}
+
+	modules, err := shell.ListModules()
+	if err != nil {
+		fmt.Printf("Error listing modules: %v\n", err)
+		return
+	}
+
+	for _, project := range projects {
+		var notes []string
+		if manifest, err := shell.LoadProjectManifest(project); err != nil {
+			notes = append(notes, "invalid project.yaml")
+		} else {
+			if manifest.Disabled {
+				notes = append(notes, "disabled")
+			}
+			if manifest.Activation.IsSet() {
+				notes = append(notes, "activated by directory")
+			}
+			if len(manifest.Lazy) > 0 {
+				notes = append(notes, "lazy: "+strings.Join(manifest.Lazy, ", "))
+			}
+		}
+
+		if len(notes) > 0 {
+			fmt.Printf("%s (%s)\n", project, strings.Join(notes, ", "))
+		} else {
+			fmt.Println(project)
+		}
+
+		found := false
+		for _, module := range modules {
+			if module.Project != project {
+				continue
+			}
+			found = true
+
+			content, err := os.ReadFile(module.Path)
+			if err != nil {
+				fmt.Printf("  %-12s unreadable: %v\n", module.Type+".sh", err)
+				continue
+			}
+			fmt.Printf("  %-12s %d lines\n", module.Type+".sh", countLines(string(content)))
+		}
+		if !found {
+			fmt.Println("  (no modules)")
+		}
+	}
+}
+
+func countLines(content string) int {
+	if content == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(content, "\n"), "\n") + 1
+}
+
+// checkProjectExists makes sure project names one of the project
+// directories, so that names like "" or ".." never reach a path outside it.
+func checkProjectExists(project string) error {
+	if err := shell.ValidateProjectName(project); err != nil {
+		return err
+	}
+
+	projects, err := shell.ListProjects()
+	if err != nil {
+		return err
+	}
+	for _, existing := range projects {
+		if existing == project {
+			return nil
+		}
+	}
+	return fmt.Errorf("project '%s' does not exist", project)
+}
+
+// checkProjectChange makes sure project exists and newName is free, so that
+// it can be renamed or copied.
+func checkProjectChange(project, newName string) error {
+	if err := checkProjectExists(project); err != nil {
+		return err
+	}
+	if err := shell.ValidateProjectName(newName); err != nil {
+		return err
+	}
+	if shell.ProjectExists(newName) {
+		return fmt.Errorf("project '%s' already exists", newName)
+	}
+	return nil
+}
+
+// checkNoConflicts refuses to move a project whose modules still have
+// unresolved sync conflicts, as the conflict state is tied to their paths.
+func checkNoConflicts(project string) error {
+	modules, err := shell.ListModules()
+	if err != nil {
+		return err
+	}
+
+	for _, module := range modules {
+		if module.Project == project && module.HasConflict() {
+			return fmt.Errorf("%s has an unresolved sync conflict, run 'dotwaifu sync' first", module.RelPath())
+		}
+	}
+	return nil
+}
+
+func runProjectRename(cmd *cobra.Command, args []string) {
+	project, newName := args[0], args[1]
+
+	if err := checkProjectChange(project, newName); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if err := checkNoConflicts(project); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if err := os.Rename(shell.GetProjectDir(project), shell.GetProjectDir(newName)); err != nil {
+		fmt.Printf("Error renaming project: %v\n", err)
+		return
+	}
+
+	fmt.Printf("✓ Renamed %s to %s\n", project, newName)
+	refreshAfterProjectChange()
+}
+
+func runProjectCopy(cmd *cobra.Command, args []string) {
+	project, newName := args[0], args[1]
+
+	if err := checkProjectChange(project, newName); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if err := copyDir(shell.GetProjectDir(project), shell.GetProjectDir(newName)); err != nil {
+		fmt.Printf("Error copying project: %v\n", err)
+		os.RemoveAll(shell.GetProjectDir(newName))
+		return
+	}
+
+	fmt.Printf("✓ Copied %s to %s\n", project, newName)
+	refreshAfterProjectChange()
+}
+
+func runProjectDelete(cmd *cobra.Command, args []string) {
+	project := args[0]
+
+	if err := checkProjectExists(project); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+	if err := checkNoConflicts(project); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if !projectDeleteYes {
+		var confirm bool
+		prompt := &survey.Confirm{
+			Message: fmt.Sprintf("Delete project '%s' and all its modules? ('dotwaifu project disable' keeps them)", project),
+			Default: false,
+		}
+		if err := survey.AskOne(prompt, &confirm); err != nil || !confirm {
+			fmt.Println("Delete cancelled.")
+			return
+		}
+	}
+
+	if err := os.RemoveAll(shell.GetProjectDir(project)); err != nil {
+		fmt.Printf("Error deleting project: %v\n", err)
+		return
+	}
+
+	fmt.Printf("✓ Deleted %s\n", project)
+	refreshAfterProjectChange()
+}
+
+func setProjectDisabled(project string, disabled bool) {
+	if err := checkProjectExists(project); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	if err := shell.SetProjectDisabled(project, disabled); err != nil {
+		fmt.Printf("Error updating %s: %v\n", shell.GetProjectManifestPath(project), err)
+		return
+	}
+
+	if disabled {
+		fmt.Printf("✓ Disabled %s; its modules are kept but no longer loaded\n", project)
+	} else {
+		fmt.Printf("✓ Enabled %s\n", project)
+	}
+	refreshAfterProjectChange()
+}
+
+func refreshAfterProjectChange() {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+
+	if err := refreshTranslations(cfg.DetectedShell); err != nil {
+		fmt.Printf("Error translating modules for %s: %v\n", cfg.DetectedShell, err)
+		return
+	}
+
+	fmt.Println("Run 'dotwaifu reload' to apply the change.")
+}
@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/shell"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sourceLoader sources the generated loader in a new bash followed by script
+// and returns what that printed on stdout.
+func sourceLoader(t *testing.T, script string) string {
+	t.Helper()
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+
+	command := exec.Command("bash", "--norc", "--noprofile", "-c", `source "$DOTWAIFU_CONFIG_ROOT/state/loader.sh"`+"\n"+script)
+	command.Env = append(command.Environ(), "DOTWAIFU_CONFIG_ROOT="+config.GetConfigDir())
+	output, err := command.Output()
+	if err != nil {
+		t.Fatalf("bash: %v", err)
+	}
+	return string(output)
+}
+
+func TestProjectRenameCopyDelete(t *testing.T) {
+	newMachine(t, map[string]string{
+		"projects/flutter/env.sh": "export FLUTTER_ROOT=/opt/flutter\n",
+		"projects/node/env.sh":    "export NODE_ENV=development\n",
+	})
+
+	runProjectRename(nil, []string{"flutter", "dart"})
+	runProjectCopy(nil, []string{"node", "deno"})
+	// Neither may overwrite an existing project
+	runProjectRename(nil, []string{"dart", "node"})
+	runProjectCopy(nil, []string{"dart", "deno"})
+
+	if got := readModule(t, "projects/dart/env.sh"); got != "export FLUTTER_ROOT=/opt/flutter\n" {
+		t.Errorf("dart/env.sh = %q", got)
+	}
+	if got := readModule(t, "projects/deno/env.sh"); got != "export NODE_ENV=development\n" {
+		t.Errorf("deno/env.sh = %q", got)
+	}
+
+	setFlag(t, &projectDeleteYes, true)
+	runProjectDelete(nil, []string{"node"})
+
+	projects, err := shell.ListProjects()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(projects, " "); got != "dart deno" {
+		t.Errorf("projects = %q, want %q", got, "dart deno")
+	}
+}
+
+func TestProjectDeleteRejectsPaths(t *testing.T) {
+	newMachine(t, map[string]string{
+		"core/env.sh":             "export EDITOR=vim\n",
+		"projects/flutter/env.sh": "export FLUTTER_ROOT=/opt/flutter\n",
+	})
+	setFlag(t, &projectDeleteYes, true)
+
+	// ".." and "" name the directory holding the projects, or the one above
+	for _, project := range []string{"..", "", "flutter/.."} {
+		runProjectDelete(nil, []string{project})
+		if err := checkProjectChange(project, "copy"); err == nil {
+			t.Errorf("checkProjectChange(%q) succeeded", project)
+		}
+	}
+
+	if got := readModule(t, "core/env.sh"); got != "export EDITOR=vim\n" {
+		t.Errorf("core/env.sh = %q", got)
+	}
+	if got := readModule(t, "projects/flutter/env.sh"); got != "export FLUTTER_ROOT=/opt/flutter\n" {
+		t.Errorf("flutter/env.sh = %q", got)
+	}
+}
+
+func TestProjectDisable(t *testing.T) {
+	newMachine(t, map[string]string{
+		"core/env.sh":             "export EDITOR=vim\n",
+		"projects/flutter/env.sh": "export FLUTTER_ROOT=/opt/flutter\n",
+	})
+
+	setProjectDisabled("flutter", true)
+	if !shell.IsProjectDisabled("flutter") {
+		t.Fatal("flutter is not disabled")
+	}
+
+	// The loader regenerated for the change skips the project
+	if got := sourceLoader(t, `echo "${FLUTTER_ROOT-unset} $EDITOR"`); got != "unset vim\n" {
+		t.Errorf("after disabling flutter got %q", got)
+	}
+
+	setProjectDisabled("flutter", false)
+	if shell.IsProjectDisabled("flutter") {
+		t.Error("flutter is still disabled")
+	}
+	if _, err := os.Stat(filepath.Join(shell.GetProjectDir("flutter"), "env.sh")); err != nil {
+		t.Error(err)
+	}
+}
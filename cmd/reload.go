@@ -6,26 +6,60 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"sort"
+	"strings"
 
 	"github.com/spf13/cobra"
 )
 
+var reloadQuiet bool
+
 var reloadCmd = &cobra.Command{
 	Use:   "reload",
 	Short: "Reload shell configuration to apply recent changes",
-	Long:  `Source your shell configuration file to apply any recent changes made through dotwaifu edit.`,
-	Run:   runReload,
+	Long: `Source your shell configuration file to apply any recent changes made through dotwaifu edit.
+
+For fish, nushell and PowerShell this also regenerates the translated copies of
+your modules (for PowerShell, the dotwaifu block in your profile).
+
+With --quiet, only the loader and the translated copies are regenerated. The
+loader runs this itself when modules changed without going through dotwaifu,
+e.g. after a git pull.`,
+	Run: runReload,
+}
+
+func init() {
+	reloadCmd.Flags().BoolVarP(&reloadQuiet, "quiet", "q", false, "Only regenerate the loader, printing nothing but errors")
 }
 
 func runReload(cmd *cobra.Command, args []string) {
 	cfg, err := config.Load()
 	if err != nil {
 		fmt.Printf("Error loading config: %v\n", err)
+		if reloadQuiet {
+			os.Exit(1)
+		}
 		return
 	}
 
 	if cfg.DetectedShell == "" {
 		fmt.Println("No shell detected. Please run 'dotwaifu init' first.")
+		if reloadQuiet {
+			os.Exit(1)
+		}
+		return
+	}
+
+	if reloadQuiet {
+		if _, err := shell.GenerateTranslations(cfg.DetectedShell); err != nil {
+			fmt.Printf("Error translating modules for %s: %v\n", cfg.DetectedShell, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if err := refreshTranslations(cfg.DetectedShell); err != nil {
+		fmt.Printf("Error translating modules for %s: %v\n", cfg.DetectedShell, err)
 		return
 	}
 
@@ -38,18 +72,55 @@ func runReload(cmd *cobra.Command, args []string) {
 		return
 	}
 
+	if integration, err := shell.CheckIntegration(cfg.DetectedShell); err == nil {
+		switch integration.Status {
+		case shell.IntegrationOutdated, shell.IntegrationModified:
+			fmt.Printf("Note: The dotwaifu loader in %s is %s, run 'dotwaifu repair' to update it.\n", rcPath, integration.Status)
+		}
+	}
+
 	fmt.Printf("Reloading shell configuration from %s...\n", rcPath)
 
 	// Execute source command
-	sourceCmd := exec.Command(cfg.DetectedShell, "-c", fmt.Sprintf("source %s", rcPath))
+	sourceCmd := exec.Command(cfg.DetectedShell, "-c", shell.GetSourceCommand(cfg.DetectedShell, rcPath))
 	sourceCmd.Env = os.Environ()
 
 	if err := sourceCmd.Run(); err != nil {
-		fmt.Printf("Note: Automatic reload failed. Please run manually: source %s\n", rcPath)
+		fmt.Printf("Note: Automatic reload failed. Please run manually: %s\n", shell.GetSourceCommand(cfg.DetectedShell, rcPath))
 		return
 	}
 
 	fmt.Println("✓ Configuration reloaded!")
 	fmt.Println("Your recent changes are now active in new terminal sessions.")
-	fmt.Printf("For this terminal, run: source %s\n", rcPath)
-}
\ No newline at end of file
+	fmt.Printf("For this terminal, run: %s\n", shell.GetSourceCommand(cfg.DetectedShell, rcPath))
+}
+
+// refreshTranslations regenerates the module copies used by shells that cannot
+// source .sh files and reports the lines that had to be left out.
+func refreshTranslations(shellName string) error {
+	untranslated, err := shell.GenerateTranslations(shellName)
+	if err != nil {
+		return err
+	}
+
+	if len(untranslated) == 0 {
+		return nil
+	}
+
+	fmt.Printf("Some lines could not be translated to %s and will be skipped:\n", shellName)
+	for _, module := range sortedKeys(untranslated) {
+		for _, statement := range untranslated[module] {
+			fmt.Printf("  %s:%d: %s\n", module, statement.Line, strings.TrimSpace(statement.Raw))
+		}
+	}
+	return nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
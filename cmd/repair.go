@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/diff"
+	"dotwaifu/internal/shell"
+	"fmt"
+	"os"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+)
+
+var repairYes bool
+
+var repairCmd = &cobra.Command{
+	Use:   "repair",
+	Short: "Upgrade or restore the dotwaifu loader in your RC file",
+	Long: `Rewrite the dotwaifu block in your RC file with the current loader.
+
+Use this after upgrading dotwaifu, or when the block was edited by hand. Only
+the lines between the dotwaifu markers are replaced; the rest of your RC file
+is left untouched. A diff is shown before anything is written.
+
+Examples:
+  dotwaifu repair        # Preview and confirm
+  dotwaifu repair --yes  # Repair without asking`,
+	Run: runRepair,
+}
+
+func init() {
+	repairCmd.Flags().BoolVarP(&repairYes, "yes", "y", false, "Write the repaired loader without asking")
+}
+
+func runRepair(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+
+	if cfg.DetectedShell == "" {
+		fmt.Println("No shell detected. Please run 'dotwaifu init' first.")
+		return
+	}
+
+	rcPath := shell.GetRCFilePath(cfg.DetectedShell)
+	integration, err := shell.CheckIntegration(cfg.DetectedShell)
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", rcPath, err)
+		return
+	}
+
+	switch integration.Status {
+	case shell.IntegrationMissing:
+		fmt.Printf("%s does not load dotwaifu. Run 'dotwaifu init' to add the loader.\n", rcPath)
+		return
+	case shell.IntegrationMalformed:
+		fmt.Printf("The dotwaifu block in %s is malformed and cannot be repaired automatically.\n", rcPath)
+		fmt.Println("Make sure it has exactly one start marker and one end marker, then run 'dotwaifu repair' again.")
+		return
+	case shell.IntegrationCurrent:
+		fmt.Printf("✅ The loader in %s is up to date (v%d).\n", rcPath, integration.Version)
+		return
+	case shell.IntegrationModified:
+		fmt.Println("Warning: the dotwaifu block was edited by hand. Repairing it discards those edits;")
+		fmt.Println("move anything you want to keep into a module first.")
+	}
+
+	current, repaired, err := shell.RepairedRC(cfg.DetectedShell)
+	if err != nil {
+		fmt.Printf("Error preparing the repair: %v\n", err)
+		return
+	}
+
+	fmt.Printf("\nChanges to %s:\n\n", rcPath)
+	fmt.Print(diff.Unified(current, repaired, rcPath, rcPath+" (repaired)"))
+	fmt.Println()
+
+	if !repairYes {
+		var confirm bool
+		prompt := &survey.Confirm{
+			Message: fmt.Sprintf("Rewrite the dotwaifu block with loader v%d?", shell.LoaderVersion),
+			Default: true,
+		}
+		if err := survey.AskOne(prompt, &confirm); err != nil || !confirm {
+			fmt.Println("Repair cancelled.")
+			return
+		}
+	}
+
+	if err := os.WriteFile(rcPath, []byte(repaired), 0644); err != nil {
+		fmt.Printf("Error writing %s: %v\n", rcPath, err)
+		return
+	}
+
+	fmt.Printf("✅ Loader in %s upgraded to v%d\n", rcPath, shell.LoaderVersion)
+	fmt.Printf("Run '%s' or open a new terminal to use it.\n", shell.GetSourceCommand(cfg.DetectedShell, rcPath))
+}
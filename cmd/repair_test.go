@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"dotwaifu/internal/shell"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestRepairRestoresEditedLoader(t *testing.T) {
+	newMachine(t, nil)
+	rcPath := shell.GetRCFilePath("bash")
+	if err := os.WriteFile(rcPath, []byte("alias g='git'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := shell.AppendToExistingRC("bash"); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(rcPath)
+	original := string(content)
+
+	edited := strings.Replace(original, "# === End dotwaifu", "export EDITED=1\n# === End dotwaifu", 1)
+	if err := os.WriteFile(rcPath, []byte(edited+"\nexport AFTER=1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	setFlag(t, &repairYes, true)
+	runRepair(nil, nil)
+
+	content, _ = os.ReadFile(rcPath)
+	if string(content) != original+"\nexport AFTER=1\n" {
+		t.Errorf("RC after repair = %q", content)
+	}
+	if integration, _ := shell.CheckIntegration("bash"); integration.Status != shell.IntegrationCurrent {
+		t.Errorf("integration after repair is %s", integration.Status)
+	}
+}
@@ -29,4 +29,18 @@ func init() {
 	rootCmd.AddCommand(exportCmd)
 	rootCmd.AddCommand(uninstallCmd)
 	rootCmd.AddCommand(syncCmd)
+	rootCmd.AddCommand(importCmd)
+	rootCmd.AddCommand(adoptCmd)
+	rootCmd.AddCommand(doctorCmd)
+	rootCmd.AddCommand(repairCmd)
+	rootCmd.AddCommand(runCmd)
+	rootCmd.AddCommand(shellCmd)
+	rootCmd.AddCommand(projectCmd)
+	rootCmd.AddCommand(buildCmd)
+	rootCmd.AddCommand(cacheCmd)
+	rootCmd.AddCommand(profileCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(bisectCmd)
+	rootCmd.AddCommand(lintCmd)
+	rootCmd.AddCommand(fmtCmd)
 }
\ No newline at end of file
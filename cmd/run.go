@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/shell"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	runProjects []string
+	runIsolated bool
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run [-p project] -- <command> [args...]",
+	Short: "Run a command with a project's environment",
+	Long: `Run a command with the PATH and environment variables of your modules,
+without loading your interactive configuration.
+
+The env.sh and paths.sh modules of core and the given projects are sourced in
+a subshell, and the command runs with the environment that produces. Aliases
+and functions are not loaded. Use --isolated to leave out the core modules.
+
+Examples:
+  dotwaifu run -p flutter -- flutter build apk
+  dotwaifu run -p flutter -p node -- make release
+  dotwaifu run -p flutter --isolated -- env`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runRun,
+}
+
+func init() {
+	runCmd.Flags().StringSliceVarP(&runProjects, "project", "p", nil, "Project whose environment to use (repeatable)")
+	runCmd.Flags().BoolVar(&runIsolated, "isolated", false, "Skip the core modules")
+	runCmd.Flags().SetInterspersed(false)
+}
+
+func runRun(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	if runIsolated && len(runProjects) == 0 {
+		fmt.Fprintln(os.Stderr, "--isolated needs at least one project (-p).")
+		os.Exit(1)
+	}
+
+	modules, err := shell.EnvironmentModules(runProjects, runIsolated)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	env, err := shell.CaptureEnvironment(cfg.DetectedShell, modules)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building environment: %v\n", err)
+		os.Exit(1)
+	}
+
+	os.Exit(execWithEnv(args, env))
+}
+
+// execWithEnv runs args with env and returns its exit status. The command is
+// looked up on the PATH from env, not the one dotwaifu was started with.
+func execWithEnv(args []string, env []string) int {
+	for _, entry := range env {
+		if path, ok := strings.CutPrefix(entry, "PATH="); ok {
+			os.Setenv("PATH", path)
+		}
+	}
+
+	command := exec.Command(args[0], args[1:]...)
+	command.Env = env
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+
+	if err := command.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return exitErr.ExitCode()
+		}
+		fmt.Fprintf(os.Stderr, "Error running %s: %v\n", args[0], err)
+		return 127
+	}
+	return 0
+}
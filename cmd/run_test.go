@@ -0,0 +1,19 @@
+package cmd
+
+import (
+	"os"
+	"testing"
+)
+
+func TestExecWithEnv(t *testing.T) {
+	// execWithEnv looks the command up on the PATH it is given
+	t.Setenv("PATH", os.Getenv("PATH"))
+	env := []string{"PATH=/usr/bin:/bin", "EXIT_STATUS=3"}
+
+	if got := execWithEnv([]string{"sh", "-c", `exit "$EXIT_STATUS"`}, env); got != 3 {
+		t.Errorf("exit status = %d, want 3", got)
+	}
+	if got := execWithEnv([]string{"dotwaifu-no-such-command"}, env); got != 127 {
+		t.Errorf("exit status of a missing command = %d, want 127", got)
+	}
+}
@@ -1,26 +1,42 @@
 package cmd
 
 import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/git"
+	"dotwaifu/internal/shell"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
 )
 
 var setupCmd = &cobra.Command{
 	Use:   "setup",
 	Short: "Setup dotwaifu from existing configuration",
-	Long:  `Setup dotwaifu from a remote repository or local path.`,
-	Run:   runSetup,
+	Long: `Setup dotwaifu from a remote repository or local path.
+
+Examples:
+  dotwaifu setup --repo user/dotfiles              # Clone from GitHub
+  dotwaifu setup --repo file:///srv/dotwaifu.git   # Clone from any git URL
+  dotwaifu setup --local ~/dotwaifu-config         # Copy an existing directory
+  dotwaifu setup --local ~/dotwaifu-config --link  # Symlink instead of copying`,
+	Run: runSetup,
 }
 
 var (
 	repoFlag  string
 	localFlag string
+	linkFlag  bool
 )
 
 func init() {
 	setupCmd.Flags().StringVarP(&repoFlag, "repo", "r", "", "Setup from Git repository (GitHub shorthand or full URL)")
 	setupCmd.Flags().StringVarP(&localFlag, "local", "l", "", "Setup from local path")
+	setupCmd.Flags().BoolVar(&linkFlag, "link", false, "Symlink the local path instead of copying it")
 }
 
 func runSetup(cmd *cobra.Command, args []string) {
@@ -29,6 +45,160 @@ func runSetup(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	fmt.Println("Setup command not yet implemented in MVP")
-	fmt.Println("Use 'dotwaifu init' for now")
-}
\ No newline at end of file
+	if repoFlag != "" && localFlag != "" {
+		fmt.Println("Please specify only one of --repo or --local")
+		return
+	}
+
+	configDir := config.GetConfigDir()
+	if entries, err := os.ReadDir(configDir); err == nil && len(entries) > 0 {
+		fmt.Printf("%s already exists and is not empty.\n", configDir)
+		fmt.Println("Run 'dotwaifu uninstall' first or move it out of the way.")
+		return
+	}
+
+	// An empty leftover directory would block cloning and symlinking
+	os.Remove(configDir)
+	if err := os.MkdirAll(filepath.Dir(configDir), 0755); err != nil {
+		fmt.Printf("Error creating config directory: %v\n", err)
+		return
+	}
+
+	if repoFlag != "" {
+		fmt.Printf("Cloning %s...\n", git.NormalizeRepoURL(repoFlag))
+		if err := git.Clone(repoFlag); err != nil {
+			fmt.Printf("Error cloning repository: %v\n", err)
+			os.RemoveAll(configDir)
+			return
+		}
+
+		if err := shell.ValidateStructure(configDir); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.RemoveAll(configDir)
+			return
+		}
+	} else {
+		source, err := filepath.Abs(expandHome(localFlag))
+		if err != nil {
+			fmt.Printf("Error resolving %s: %v\n", localFlag, err)
+			return
+		}
+
+		if err := shell.ValidateStructure(source); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
+
+		if linkFlag {
+			fmt.Printf("Linking %s -> %s\n", configDir, source)
+			err = os.Symlink(source, configDir)
+		} else {
+			fmt.Printf("Copying %s to %s...\n", source, configDir)
+			err = copyDir(source, configDir)
+		}
+
+		if err != nil {
+			fmt.Printf("Error setting up configuration: %v\n", err)
+			// A failed symlink leaves nothing behind, and configDir may not
+			// be ours then
+			if !linkFlag {
+				os.RemoveAll(configDir)
+			}
+			return
+		}
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+
+	detectedShell := shell.DetectShell()
+	fmt.Printf("Detected shell: %s\n", detectedShell)
+	if detectedShell == "unknown" {
+		fmt.Println("Warning: Unable to detect shell. Defaulting to zsh.")
+		detectedShell = "zsh"
+	}
+	cfg.DetectedShell = detectedShell
+
+	if cfg.PreferredEditor == "" {
+		editorPrompt := &survey.Input{
+			Message: "What editor do you use for editing files?",
+			Default: "code",
+		}
+		if err := survey.AskOne(editorPrompt, &cfg.PreferredEditor); err != nil {
+			fmt.Printf("Error during setup: %v\n", err)
+			return
+		}
+	}
+
+	if err := cfg.Save(); err != nil {
+		fmt.Printf("Error saving configuration: %v\n", err)
+		return
+	}
+
+	if err := installIntegration(detectedShell); err != nil {
+		fmt.Printf("Error adding integration: %v\n", err)
+		return
+	}
+
+	fmt.Println("\nSetup complete!")
+	fmt.Printf("Config files location: %s\n", configDir)
+	fmt.Printf("Restart your shell or run: source %s\n", shell.GetRCFilePath(detectedShell))
+}
+
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, strings.TrimPrefix(path, "~"))
+	}
+	return path
+}
+
+func copyDir(src, dst string) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		default:
+			return copyFile(path, target, info.Mode().Perm())
+		}
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+
+	return out.Close()
+}
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/shell"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// newBareRemote creates an empty bare repository to sync through. The file
+// transport runs git-upload-pack and git-receive-pack, so it needs git.
+func newBareRemote(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	dir := t.TempDir()
+	if _, err := git.PlainInit(dir, true); err != nil {
+		t.Fatal(err)
+	}
+	return "file://" + dir
+}
+
+// setFlag sets one of the package level flag variables for the rest of the
+// test.
+func setFlag[T any](t *testing.T, flag *T, value T) {
+	old := *flag
+	*flag = value
+	t.Cleanup(func() { *flag = old })
+}
+
+func writeModule(t *testing.T, path, content string) {
+	t.Helper()
+	fullPath := filepath.Join(config.GetConfigDir(), "shell", "shared", filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readModule(t *testing.T, path string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(config.GetConfigDir(), "shell", "shared", filepath.FromSlash(path)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+// newMachine points HOME at a fresh directory with a configuration holding
+// the given modules and returns it. Tests switch between machines by setting
+// HOME again.
+func newMachine(t *testing.T, modules map[string]string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SHELL", "/bin/bash")
+
+	cfg := &config.Config{DetectedShell: "bash", PreferredEditor: "vim"}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	for path, content := range modules {
+		writeModule(t, path, content)
+	}
+	return home
+}
+
+// publish commits the configuration of the current machine and pushes it to
+// remote.
+func publish(t *testing.T, remote string) {
+	t.Helper()
+	repo, err := git.PlainInit(config.GetConfigDir(), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := worktree.AddGlob("."); err != nil {
+		t.Fatal(err)
+	}
+	signature := &object.Signature{Name: "test", Email: "test@local", When: time.Now()}
+	if _, err := worktree.Commit("Initial configuration", &git.CommitOptions{Author: signature}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.CreateRemote(&gitconfig.RemoteConfig{Name: "origin", URLs: []string{remote}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.Push(&git.PushOptions{RemoteName: "origin"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// setupFrom sets up a new, empty machine from remote.
+func setupFrom(t *testing.T, remote string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("SHELL", "/bin/bash")
+
+	setFlag(t, &repoFlag, remote)
+	runSetup(nil, nil)
+	return home
+}
+
+func TestSetupFromRepo(t *testing.T) {
+	remote := newBareRemote(t)
+	newMachine(t, map[string]string{"core/aliases.sh": "alias g='git'\n"})
+	publish(t, remote)
+
+	setupFrom(t, remote)
+
+	if got := readModule(t, "core/aliases.sh"); got != "alias g='git'\n" {
+		t.Fatalf("aliases.sh after setup = %q", got)
+	}
+	if rc, err := os.ReadFile(shell.GetRCFilePath("bash")); err != nil || !strings.Contains(string(rc), "DOTWAIFU_CONFIG_ROOT") {
+		t.Errorf("setup did not add the loader to .bashrc: %v", err)
+	}
+}
+
+func TestSetupFromLocal(t *testing.T) {
+	source := newMachine(t, map[string]string{"core/env.sh": "export EDITOR=\"vim\"\n"})
+
+	t.Setenv("HOME", t.TempDir())
+	setFlag(t, &localFlag, filepath.Join(source, ".config", "dotwaifu"))
+	runSetup(nil, nil)
+
+	if got := readModule(t, "core/env.sh"); got != "export EDITOR=\"vim\"\n" {
+		t.Errorf("env.sh after setup = %q", got)
+	}
+	if info, err := os.Lstat(config.GetConfigDir()); err != nil || !info.IsDir() {
+		t.Errorf("config directory is not a copy: %v", err)
+	}
+}
+
+func TestSetupFromLocalLink(t *testing.T) {
+	source := newMachine(t, map[string]string{"core/env.sh": "export EDITOR=\"vim\"\n"})
+	sourceDir := filepath.Join(source, ".config", "dotwaifu")
+
+	t.Setenv("HOME", t.TempDir())
+	setFlag(t, &localFlag, sourceDir)
+	setFlag(t, &linkFlag, true)
+	runSetup(nil, nil)
+
+	if target, err := os.Readlink(config.GetConfigDir()); err != nil || target != sourceDir {
+		t.Errorf("config directory links to %q, %v, want %q", target, err, sourceDir)
+	}
+}
+
+func TestSetupRejectsInvalidStructure(t *testing.T) {
+	source := t.TempDir()
+
+	t.Setenv("HOME", t.TempDir())
+	setFlag(t, &localFlag, source)
+	runSetup(nil, nil)
+
+	if _, err := os.Stat(config.GetConfigDir()); !os.IsNotExist(err) {
+		t.Errorf("config directory created from a directory without shell/shared/core: %v", err)
+	}
+}
+
+func TestSetupRemovesConfigDirWhenCopyFails(t *testing.T) {
+	source := newMachine(t, map[string]string{"core/env.sh": "export EDITOR=\"vim\"\n"})
+	sourceDir := filepath.Join(source, ".config", "dotwaifu")
+
+	// A socket cannot be opened for copying, so the copy fails half way
+	listener, err := net.Listen("unix", filepath.Join(sourceDir, "shell", "shared", "core", "sock"))
+	if err != nil {
+		t.Skipf("cannot create a socket: %v", err)
+	}
+	defer listener.Close()
+
+	t.Setenv("HOME", t.TempDir())
+	setFlag(t, &localFlag, sourceDir)
+	runSetup(nil, nil)
+
+	if _, err := os.Stat(config.GetConfigDir()); !os.IsNotExist(err) {
+		t.Errorf("config directory left behind after a failed copy: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/shell"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var shellCmd = &cobra.Command{
+	Use:   "shell <project> [project...]",
+	Short: "Start a subshell with only the given projects loaded",
+	Long: `Start a new zsh or bash session that loads your core modules and only the
+named projects, ignoring activation rules. Your main shell is left alone; exit
+the subshell to return to it.
+
+DOTWAIFU_ACTIVE_PROJECTS is set to the loaded projects, so your prompt can
+show them.
+
+Examples:
+  dotwaifu shell flutter
+  dotwaifu shell flutter node`,
+	Args: cobra.MinimumNArgs(1),
+	Run:  runShell,
+}
+
+func runShell(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+
+	if cfg.DetectedShell != "zsh" && cfg.DetectedShell != "bash" {
+		fmt.Println("dotwaifu shell only works with zsh and bash.")
+		return
+	}
+
+	loader, err := shell.LoaderScript(args)
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
+
+	tempDir, err := os.MkdirTemp("", "dotwaifu-shell-")
+	if err != nil {
+		fmt.Printf("Error creating temporary RC: %v\n", err)
+		return
+	}
+	defer os.RemoveAll(tempDir)
+
+	rcPath := filepath.Join(tempDir, shell.GetRCFileName(cfg.DetectedShell))
+	content := "DOTWAIFU_CONFIG_ROOT=\"$HOME/.config/dotwaifu\"\n\n" + loader
+
+	var command *exec.Cmd
+	if cfg.DetectedShell == "zsh" {
+		// zsh has no --rcfile; point ZDOTDIR at the temporary RC and restore
+		// it once the RC runs
+		content = `if [ -n "${DOTWAIFU_ZDOTDIR+set}" ]; then
+    ZDOTDIR=$DOTWAIFU_ZDOTDIR
+else
+    unset ZDOTDIR
+fi
+unset DOTWAIFU_ZDOTDIR
+
+` + content
+
+		command = exec.Command("zsh", "-i")
+		command.Env = append(os.Environ(), "ZDOTDIR="+tempDir)
+		if zdotdir, ok := os.LookupEnv("ZDOTDIR"); ok {
+			command.Env = append(command.Env, "DOTWAIFU_ZDOTDIR="+zdotdir)
+		}
+	} else {
+		command = exec.Command("bash", "--rcfile", rcPath, "-i")
+		command.Env = os.Environ()
+	}
+
+	if err := os.WriteFile(rcPath, []byte(content), 0600); err != nil {
+		fmt.Printf("Error writing temporary RC: %v\n", err)
+		return
+	}
+
+	command.Stdin = os.Stdin
+	command.Stdout = os.Stdout
+	command.Stderr = os.Stderr
+
+	fmt.Printf("Starting %s with core + %v loaded. Type 'exit' to leave.\n", cfg.DetectedShell, args)
+	if err := command.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			fmt.Printf("Error starting %s: %v\n", cfg.DetectedShell, err)
+		}
+	}
+}
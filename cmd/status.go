@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"dotwaifu/internal/shell"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var statusJSON bool
+
+var statusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show which modules failed to load on the last shell start",
+	Long: `The loader sources every module on its own: when one fails, the shell
+prints a one-line warning naming it and keeps loading the others. The failures
+are recorded in ~/.config/dotwaifu/state/status, which this command reads.
+
+A module counts as failed when sourcing it returns an error status and the
+shell printed an error for it, e.g. on a syntax error or when its last command
+is not found.
+
+Exits with a non-zero status if any module failed.
+
+Examples:
+  dotwaifu status
+  dotwaifu status --json`,
+	Args: cobra.NoArgs,
+	Run:  runStatus,
+}
+
+type moduleFailureReport struct {
+	Module       string `json:"module"`
+	Reason       string `json:"reason"`
+	ChangedSince bool   `json:"changed_since"`
+}
+
+type statusReport struct {
+	Time     string                `json:"time"`
+	Shell    string                `json:"shell"`
+	Failures []moduleFailureReport `json:"failures"`
+}
+
+func init() {
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "Print the status as JSON")
+}
+
+func runStatus(cmd *cobra.Command, args []string) {
+	status, err := shell.ReadStatus()
+	if err != nil {
+		fmt.Printf("Error reading %s: %v\n", shell.GetStatusPath(), err)
+		os.Exit(1)
+	}
+
+	if status == nil {
+		fmt.Println("No shell start recorded yet. Open a new shell to record one.")
+		return
+	}
+
+	if statusJSON {
+		report := statusReport{
+			Time:     status.Time.Format(time.RFC3339),
+			Shell:    status.Shell,
+			Failures: []moduleFailureReport{},
+		}
+		for _, failure := range status.Failures {
+			report.Failures = append(report.Failures, moduleFailureReport{
+				Module:       failure.Module,
+				Reason:       failure.Reason,
+				ChangedSince: status.ChangedSince(failure),
+			})
+		}
+
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Printf("Error encoding status: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+	} else {
+		fmt.Printf("Last shell start: %s (%s)\n\n", status.Time.Format("2006-01-02 15:04:05"), status.Shell)
+		if len(status.Failures) == 0 {
+			fmt.Println("✅ All modules loaded")
+			return
+		}
+
+		for _, failure := range status.Failures {
+			fmt.Printf("  ✗ %s: %s\n", failure.Module, failure.Reason)
+			if status.ChangedSince(failure) {
+				fmt.Println("    changed since, open a new shell to check again")
+			}
+		}
+		fmt.Printf("\n%d module(s) failed to load. Fix them with 'dotwaifu edit'.\n", len(status.Failures))
+	}
+
+	if len(status.Failures) > 0 {
+		os.Exit(1)
+	}
+}
@@ -1,20 +1,58 @@
 package cmd
 
 import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/diff"
 	"dotwaifu/internal/git"
 	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
 
+	"github.com/AlecAivazis/survey/v2"
 	"github.com/spf13/cobra"
 )
 
 var syncCmd = &cobra.Command{
 	Use:   "sync",
 	Short: "Sync configuration changes with git",
-	Long:  `Add, commit, and push configuration changes to git repository.`,
-	Run:   runSync,
+	Long: `Pull, commit, and push configuration changes to git repository.
+
+When both this machine and the remote changed the configuration, sync merges
+the histories file by file. Files that cannot be merged automatically get
+conflict markers and are not loaded by your shell until you resolve them.
+
+Examples:
+  dotwaifu sync                                   # Commit, pull and push
+  dotwaifu sync --remote git@github.com:me/dots   # Set the remote, then sync
+  dotwaifu sync --abort                           # Give up on a conflicted merge`,
+	Run: runSync,
+}
+
+var (
+	remoteFlag string
+	abortFlag  bool
+)
+
+const (
+	conflictEdit   = "Open in editor"
+	conflictOurs   = "Keep my version"
+	conflictTheirs = "Keep the remote version"
+	conflictSkip   = "Skip for now"
+)
+
+func init() {
+	syncCmd.Flags().StringVar(&remoteFlag, "remote", "", "Set the git remote to sync with (GitHub shorthand or full URL)")
+	syncCmd.Flags().BoolVar(&abortFlag, "abort", false, "Abort an in-progress merge and restore the last local commit")
 }
 
 func runSync(cmd *cobra.Command, args []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Error loading config: %v\n", err)
+		return
+	}
+
 	if !git.IsGitRepository() {
 		fmt.Println("Initializing git repository...")
 		if err := git.InitRepository(); err != nil {
@@ -24,6 +62,65 @@ func runSync(cmd *cobra.Command, args []string) {
 		fmt.Println("Git repository initialized.")
 	}
 
+	if abortFlag {
+		if !git.MergeInProgress() {
+			fmt.Println("No merge in progress.")
+			return
+		}
+		if err := git.AbortMerge(); err != nil {
+			fmt.Printf("Error aborting merge: %v\n", err)
+			return
+		}
+		fmt.Println("Merge aborted. Your last local commit has been restored.")
+		return
+	}
+
+	if err := git.EnsureIgnoreFile(); err != nil {
+		fmt.Printf("Error updating .gitignore: %v\n", err)
+		return
+	}
+
+	if git.MergeInProgress() {
+		fmt.Println("Resuming merge with unresolved conflicts...")
+		if !finishMerge(cfg) {
+			return
+		}
+	}
+
+	remote, err := resolveRemote(cfg)
+	if err != nil {
+		fmt.Printf("Error configuring remote: %v\n", err)
+		return
+	}
+
+	if remote != "" {
+		fmt.Printf("Fetching from %s...\n", remote)
+		if err := git.Fetch(); err != nil {
+			fmt.Printf("Error fetching: %v\n", err)
+			return
+		}
+
+		status, err := git.GetStatus()
+		if err != nil {
+			fmt.Printf("Error getting git status: %v\n", err)
+			return
+		}
+
+		ahead, behind, err := git.AheadBehind()
+		if err != nil {
+			fmt.Printf("Error comparing with remote: %v\n", err)
+			return
+		}
+
+		if behind > 0 && ahead == 0 && status.IsClean() {
+			fmt.Printf("Pulling %d new commit(s)...\n", behind)
+			if err := git.FastForward(); err != nil {
+				fmt.Printf("Error pulling changes: %v\n", err)
+				return
+			}
+		}
+	}
+
 	status, err := git.GetStatus()
 	if err != nil {
 		fmt.Printf("Error getting git status: %v\n", err)
@@ -31,18 +128,226 @@ func runSync(cmd *cobra.Command, args []string) {
 	}
 
 	if status.IsClean() {
-		fmt.Println("No changes to sync.")
+		fmt.Println("No local changes to commit.")
+	} else {
+		if !runPreCommit(cfg) {
+			return
+		}
+		fmt.Println("Committing changes...")
+		if err := git.AddAndCommit("Update dotwaifu configuration"); err != nil {
+			fmt.Printf("Error committing changes: %v\n", err)
+			return
+		}
+		fmt.Println("✅ Changes committed successfully!")
+	}
+
+	if remote == "" {
+		fmt.Println("Note: No remote configured. To push to a remote repository, run:")
+		fmt.Println("  dotwaifu sync --remote <your-repo-url>")
 		return
 	}
 
-	fmt.Println("Committing changes...")
-	if err := git.AddAndCommit("Update dotwaifu configuration"); err != nil {
-		fmt.Printf("Error committing changes: %v\n", err)
+	ahead, behind, err := git.AheadBehind()
+	if err != nil {
+		fmt.Printf("Error comparing with remote: %v\n", err)
 		return
 	}
 
-	fmt.Println("✅ Changes committed successfully!")
-	fmt.Println("Note: To push to a remote repository, add a remote and push manually:")
-	fmt.Println("  git remote add origin <your-repo-url>")
-	fmt.Println("  git push -u origin main")
-}
\ No newline at end of file
+	if behind > 0 && ahead == 0 {
+		fmt.Printf("Pulling %d new commit(s)...\n", behind)
+		if err := git.FastForward(); err != nil {
+			fmt.Printf("Error pulling changes: %v\n", err)
+			return
+		}
+	} else if behind > 0 {
+		fmt.Printf("Local and remote histories have diverged (%d ahead, %d behind). Merging...\n", ahead, behind)
+		result, err := git.MergeRemote()
+		if err != nil {
+			fmt.Printf("Error merging remote changes: %v\n", err)
+			return
+		}
+
+		for _, path := range result.Updated {
+			fmt.Printf("  merged     %s\n", path)
+		}
+		for _, path := range result.Conflicts {
+			fmt.Printf("  CONFLICT   %s\n", path)
+		}
+
+		if len(result.Conflicts) > 0 && !finishMerge(cfg) {
+			return
+		}
+	}
+
+	ahead, _, err = git.AheadBehind()
+	if err != nil {
+		fmt.Printf("Error comparing with remote: %v\n", err)
+		return
+	}
+
+	if ahead > 0 {
+		fmt.Printf("Pushing %d commit(s)...\n", ahead)
+		if err := git.Push(); err != nil {
+			fmt.Printf("Error pushing: %v\n", err)
+			return
+		}
+	}
+
+	ahead, behind, err = git.AheadBehind()
+	if err != nil {
+		fmt.Printf("Error comparing with remote: %v\n", err)
+		return
+	}
+
+	if err := refreshTranslations(cfg.DetectedShell); err != nil {
+		fmt.Printf("Error translating modules for %s: %v\n", cfg.DetectedShell, err)
+		return
+	}
+
+	fmt.Printf("✅ In sync with %s (%d ahead, %d behind)\n", remote, ahead, behind)
+}
+
+// resolveRemote applies --remote when given and otherwise falls back to the
+// remote stored in config.yaml or already present in the repository.
+func resolveRemote(cfg *config.Config) (string, error) {
+	if remoteFlag != "" {
+		if err := git.SetRemote(remoteFlag); err != nil {
+			return "", err
+		}
+
+		cfg.Remote = git.NormalizeRepoURL(remoteFlag)
+		if err := cfg.Save(); err != nil {
+			return "", err
+		}
+		fmt.Printf("Remote set to %s\n", cfg.Remote)
+		return cfg.Remote, nil
+	}
+
+	existing, err := git.GetRemoteURL()
+	if err != nil {
+		return "", err
+	}
+
+	if cfg.Remote != "" && existing != cfg.Remote {
+		if err := git.SetRemote(cfg.Remote); err != nil {
+			return "", err
+		}
+		return cfg.Remote, nil
+	}
+
+	return existing, nil
+}
+
+// finishMerge walks the user through every unresolved conflict and records the
+// merge commit once none are left. It returns false while conflicts remain.
+func finishMerge(cfg *config.Config) bool {
+	conflicts, err := git.ListConflicts()
+	if err != nil {
+		fmt.Printf("Error listing conflicts: %v\n", err)
+		return false
+	}
+
+	for _, path := range conflicts {
+		if err := resolveConflict(cfg, path); err != nil {
+			fmt.Printf("Error resolving %s: %v\n", path, err)
+			return false
+		}
+	}
+
+	remaining, err := git.ListConflicts()
+	if err != nil {
+		fmt.Printf("Error listing conflicts: %v\n", err)
+		return false
+	}
+
+	if len(remaining) > 0 {
+		fmt.Printf("\n%d file(s) still have conflicts and will not be loaded by your shell:\n", len(remaining))
+		for _, path := range remaining {
+			fmt.Printf("  %s\n", path)
+		}
+		fmt.Println("Run 'dotwaifu sync' again to resolve them, or 'dotwaifu sync --abort' to give up.")
+		return false
+	}
+
+	if err := git.CompleteMerge(); err != nil {
+		fmt.Printf("Error completing merge: %v\n", err)
+		return false
+	}
+
+	fmt.Println("✅ Merge completed.")
+	return true
+}
+
+// runPreCommit runs the pre_commit commands from config.yaml in the config
+// directory and reports whether all of them passed.
+func runPreCommit(cfg *config.Config) bool {
+	for _, command := range cfg.PreCommit {
+		fmt.Printf("Running pre-commit check: %s\n", command)
+		check := exec.Command("sh", "-c", command)
+		check.Dir = config.GetConfigDir()
+		check.Stdout = os.Stdout
+		check.Stderr = os.Stderr
+		if err := check.Run(); err != nil {
+			fmt.Printf("Pre-commit check '%s' failed (%v), nothing was committed.\n", command, err)
+			fmt.Println("Fix the problems and run 'dotwaifu sync' again.")
+			return false
+		}
+	}
+	return true
+}
+
+func resolveConflict(cfg *config.Config, path string) error {
+	fullPath := filepath.Join(config.GetConfigDir(), filepath.FromSlash(path))
+
+	for {
+		var action string
+		prompt := &survey.Select{
+			Message: fmt.Sprintf("%s was changed on both machines. How do you want to resolve it?", path),
+			Options: []string{conflictEdit, conflictOurs, conflictTheirs, conflictSkip},
+		}
+		if err := survey.AskOne(prompt, &action); err != nil {
+			return err
+		}
+
+		switch action {
+		case conflictOurs:
+			return git.ResolveWith(path, git.Ours)
+		case conflictTheirs:
+			return git.ResolveWith(path, git.Theirs)
+		case conflictSkip:
+			return nil
+		}
+
+		if cfg.PreferredEditor == "" {
+			fmt.Printf("No editor configured. Edit %s manually, then run 'dotwaifu sync' again.\n", fullPath)
+			return nil
+		}
+
+		if err := openInEditor(cfg.PreferredEditor, fullPath); err != nil {
+			return err
+		}
+
+		content, err := os.ReadFile(fullPath)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		if diff.HasConflictMarkers(string(content)) {
+			fmt.Printf("%s still contains conflict markers.\n", path)
+			continue
+		}
+
+		var resolved bool
+		confirm := &survey.Confirm{
+			Message: fmt.Sprintf("Mark %s as resolved?", path),
+			Default: true,
+		}
+		if err := survey.AskOne(confirm, &resolved); err != nil {
+			return err
+		}
+
+		if resolved {
+			return git.MarkResolved(path)
+		}
+	}
+}
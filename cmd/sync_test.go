@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/git"
+	"strings"
+	"testing"
+)
+
+func TestSyncRoundTrip(t *testing.T) {
+	remote := newBareRemote(t)
+	first := newMachine(t, map[string]string{"core/aliases.sh": "alias g='git'\n"})
+	setFlag(t, &remoteFlag, remote)
+	runSync(nil, nil)
+	setFlag(t, &remoteFlag, "")
+
+	// A change made on a second machine comes back to the first one
+	setupFrom(t, remote)
+	writeModule(t, "core/aliases.sh", "alias g='git'\nalias k='kubectl'\n")
+	runSync(nil, nil)
+
+	t.Setenv("HOME", first)
+	runSync(nil, nil)
+
+	if got := readModule(t, "core/aliases.sh"); !strings.Contains(got, "alias k='kubectl'") {
+		t.Errorf("aliases.sh after sync = %q", got)
+	}
+}
+
+func TestSyncStoresRemote(t *testing.T) {
+	remote := newBareRemote(t)
+	newMachine(t, map[string]string{"core/aliases.sh": "alias g='git'\n"})
+	setFlag(t, &remoteFlag, remote)
+	runSync(nil, nil)
+	setFlag(t, &remoteFlag, "")
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Remote != remote {
+		t.Errorf("remote in config.yaml = %q, want %q", cfg.Remote, remote)
+	}
+
+	// setup brings the remote along in config.yaml
+	setupFrom(t, remote)
+	if cfg, err = config.Load(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Remote != remote {
+		t.Errorf("remote after setup = %q, want %q", cfg.Remote, remote)
+	}
+}
+
+func TestSyncMergesDivergedChanges(t *testing.T) {
+	remote := newBareRemote(t)
+	first := newMachine(t, map[string]string{"core/env.sh": "export EDITOR=\"vim\"\n"})
+	setFlag(t, &remoteFlag, remote)
+	runSync(nil, nil)
+	setFlag(t, &remoteFlag, "")
+
+	setupFrom(t, remote)
+	writeModule(t, "core/aliases.sh", "alias g='git'\n")
+	runSync(nil, nil)
+
+	// Both machines changed a different file, so the merge is clean
+	t.Setenv("HOME", first)
+	writeModule(t, "core/env.sh", "export EDITOR=\"nvim\"\n")
+	runSync(nil, nil)
+
+	if got := readModule(t, "core/env.sh"); got != "export EDITOR=\"nvim\"\n" {
+		t.Errorf("env.sh after sync = %q", got)
+	}
+	if got := readModule(t, "core/aliases.sh"); got != "alias g='git'\n" {
+		t.Errorf("aliases.sh after sync = %q", got)
+	}
+}
+
+func TestSyncAbort(t *testing.T) {
+	remote := newBareRemote(t)
+	first := newMachine(t, map[string]string{"core/env.sh": "export EDITOR=\"vim\"\n"})
+	setFlag(t, &remoteFlag, remote)
+	runSync(nil, nil)
+	setFlag(t, &remoteFlag, "")
+
+	setupFrom(t, remote)
+	writeModule(t, "core/env.sh", "export EDITOR=\"nvim\"\n")
+	runSync(nil, nil)
+
+	// Merge a conflicting edit the way sync does, without the prompt that
+	// follows
+	t.Setenv("HOME", first)
+	writeModule(t, "core/env.sh", "export EDITOR=\"nano\"\n")
+	if err := git.AddAndCommit("Switch to nano"); err != nil {
+		t.Fatal(err)
+	}
+	if err := git.Fetch(); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := git.MergeRemote(); err != nil || len(result.Conflicts) != 1 {
+		t.Fatalf("MergeRemote = %+v, %v, want one conflict", result, err)
+	}
+
+	setFlag(t, &abortFlag, true)
+	runSync(nil, nil)
+
+	if got := readModule(t, "core/env.sh"); got != "export EDITOR=\"nano\"\n" {
+		t.Errorf("env.sh after abort = %q", got)
+	}
+	if git.MergeInProgress() {
+		t.Error("merge still in progress after sync --abort")
+	}
+}
+
+func TestRunPreCommit(t *testing.T) {
+	newMachine(t, map[string]string{"core/env.sh": "export EDITOR=\"vim\"\n"})
+
+	// The commands run in the config directory
+	cfg := &config.Config{PreCommit: []string{"test -f shell/shared/core/env.sh", "true"}}
+	if !runPreCommit(cfg) {
+		t.Error("runPreCommit failed with passing checks")
+	}
+
+	cfg.PreCommit = append(cfg.PreCommit, "false")
+	if runPreCommit(cfg) {
+		t.Error("runPreCommit passed with a failing check")
+	}
+}
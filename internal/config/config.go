@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -12,6 +13,30 @@ type Config struct {
 	PreferredEditor string `yaml:"preferred_editor"`
 	InitBasic       bool   `yaml:"init_basic"`
 	CreateExamples  bool   `yaml:"create_examples"`
+	Remote          string `yaml:"remote"`
+	// Types add config types or override the defaults, see ConfigTypes
+	Types []ConfigType `yaml:"types,omitempty"`
+	// ProfileThresholdMs is the load time above which doctor reports a
+	// module as slow
+	ProfileThresholdMs int `yaml:"profile_threshold_ms,omitempty"`
+	// Lint overrides the severity of lint rules by name, see LintSeverity
+	Lint map[string]string `yaml:"lint,omitempty"`
+	// FmtSort makes 'dotwaifu fmt' sort the statements within each block
+	FmtSort bool `yaml:"fmt_sort,omitempty"`
+	// PreCommit are shell commands 'dotwaifu sync' runs before committing;
+	// it stops if one of them fails
+	PreCommit []string `yaml:"pre_commit,omitempty"`
+}
+
+// DefaultProfileThreshold applies when profile_threshold_ms is not set.
+const DefaultProfileThreshold = 100 * time.Millisecond
+
+// ProfileThreshold returns the load time above which a module is slow.
+func (c *Config) ProfileThreshold() time.Duration {
+	if c.ProfileThresholdMs > 0 {
+		return time.Duration(c.ProfileThresholdMs) * time.Millisecond
+	}
+	return DefaultProfileThreshold
 }
 
 func GetConfigDir() string {
@@ -19,6 +44,12 @@ func GetConfigDir() string {
 	return filepath.Join(home, ".config", "dotwaifu")
 }
 
+// GetStateDir returns the machine-local directory for runtime state. It lives
+// inside the config directory but is ignored by git.
+func GetStateDir() string {
+	return filepath.Join(GetConfigDir(), "state")
+}
+
 func GetConfigPath() string {
 	return filepath.Join(GetConfigDir(), "config.yaml")
 }
@@ -36,8 +67,13 @@ func Load() (*Config, error) {
 	}
 
 	var config Config
-	err = yaml.Unmarshal(data, &config)
-	return &config, err
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return &config, err
+	}
+	if err := config.ValidateTypes(); err != nil {
+		return &config, err
+	}
+	return &config, config.ValidateLint()
 }
 
 func (c *Config) Save() error {
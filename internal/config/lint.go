@@ -0,0 +1,34 @@
+package config
+
+import "fmt"
+
+// Severities of lint rules. Errors make 'dotwaifu lint' fail and stop
+// 'dotwaifu edit' from saving; rules set to off are not checked.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+	SeverityOff     = "off"
+)
+
+// ValidateLint checks the rule severities configured in config.yaml. Rule
+// names are checked by 'dotwaifu lint', which knows them.
+func (c *Config) ValidateLint() error {
+	for rule, severity := range c.Lint {
+		switch severity {
+		case SeverityError, SeverityWarning, SeverityInfo, SeverityOff:
+		default:
+			return fmt.Errorf("lint rule %s: invalid severity %q: use error, warning, info or off", rule, severity)
+		}
+	}
+	return nil
+}
+
+// LintSeverity returns the configured severity of rule, or fallback if
+// config.yaml does not set one.
+func (c *Config) LintSeverity(rule, fallback string) string {
+	if severity, ok := c.Lint[rule]; ok {
+		return severity
+	}
+	return fallback
+}
@@ -0,0 +1,143 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var typeNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ConfigType is a kind of module, such as aliases or env. Every type is a
+// <name>.sh file in core and in each project.
+type ConfigType struct {
+	Name string `yaml:"name"`
+	// Description is shown by 'dotwaifu edit' and heads new modules
+	Description string `yaml:"description,omitempty"`
+	// Template is written below the header of a new core module
+	Template string `yaml:"template,omitempty"`
+	// Order is the load order; lower loads first, and types without an
+	// order load after the others
+	Order int `yaml:"order,omitempty"`
+}
+
+// DefaultTypes are the types every configuration has. Entries under types in
+// config.yaml override them by name or add new ones. The orders keep the
+// alphabetical load order of earlier versions.
+var DefaultTypes = []ConfigType{
+	{Name: "aliases", Description: "aliases", Template: "# Example: alias ll=\"ls -la\"\n", Order: 10},
+	{Name: "env", Description: "environment variables", Template: "# Example: export EDITOR=\"code\"\n", Order: 20},
+	{Name: "paths", Description: "PATH modifications", Template: "# Example: export PATH=\"$HOME/bin:$PATH\"\n", Order: 30},
+	{Name: "scripts", Description: "utility scripts", Template: "# Example: function mkcd() { mkdir -p \"$1\" && cd \"$1\"; }\n", Order: 40},
+}
+
+// ValidateTypes checks the types configured in config.yaml.
+func (c *Config) ValidateTypes() error {
+	seen := make(map[string]bool)
+	for _, t := range c.Types {
+		if !typeNamePattern.MatchString(t.Name) {
+			return fmt.Errorf("invalid config type name %q: use letters, digits, '-' and '_'", t.Name)
+		}
+		if seen[t.Name] {
+			return fmt.Errorf("config type %q is defined twice", t.Name)
+		}
+		seen[t.Name] = true
+	}
+	return nil
+}
+
+// ConfigTypes returns the registry of config types in load order: the
+// defaults merged with the types from config.yaml.
+func (c *Config) ConfigTypes() []ConfigType {
+	types := make([]ConfigType, len(DefaultTypes))
+	copy(types, DefaultTypes)
+
+	for _, configured := range c.Types {
+		merged := false
+		for i := range types {
+			if types[i].Name != configured.Name {
+				continue
+			}
+			if configured.Description != "" {
+				types[i].Description = configured.Description
+			}
+			if configured.Template != "" {
+				types[i].Template = configured.Template
+			}
+			if configured.Order != 0 {
+				types[i].Order = configured.Order
+			}
+			merged = true
+		}
+		if !merged {
+			types = append(types, configured)
+		}
+	}
+
+	sort.SliceStable(types, func(i, j int) bool {
+		a, b := types[i].loadRank(), types[j].loadRank()
+		if a != b {
+			return a < b
+		}
+		return types[i].Name < types[j].Name
+	})
+	return types
+}
+
+// LookupType finds a config type by name.
+func (c *Config) LookupType(name string) (ConfigType, bool) {
+	for _, t := range c.ConfigTypes() {
+		if t.Name == name {
+			return t, true
+		}
+	}
+	return ConfigType{}, false
+}
+
+// TypeNames returns the names of the config types in load order.
+func (c *Config) TypeNames() []string {
+	var names []string
+	for _, t := range c.ConfigTypes() {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// LoadTypes loads config.yaml and returns its config types in load order.
+func LoadTypes() ([]ConfigType, error) {
+	cfg, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.ConfigTypes(), nil
+}
+
+// CoreHeader is the content of a new core module of type t.
+func (t ConfigType) CoreHeader() string {
+	content := "# Global " + t.describe() + "\n" + t.Template
+	if !strings.HasSuffix(content, "\n") {
+		content += "\n"
+	}
+	return content
+}
+
+// ProjectHeader is the content of a new module of type t in project.
+func (t ConfigType) ProjectHeader(project string) string {
+	return "# " + project + " " + t.describe() + "\n"
+}
+
+func (t ConfigType) loadRank() int {
+	if t.Order == 0 {
+		return math.MaxInt
+	}
+	return t.Order
+}
+
+func (t ConfigType) describe() string {
+	if t.Description == "" {
+		return t.Name
+	}
+	return t.Description
+}
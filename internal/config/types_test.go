@@ -0,0 +1,65 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigTypes(t *testing.T) {
+	cfg := &Config{Types: []ConfigType{
+		{Name: "completions", Description: "shell completions", Order: 50},
+		{Name: "paths", Order: 5},
+		{Name: "misc"},
+		{Name: "env", Description: "environment"},
+	}}
+
+	want := []string{"paths", "aliases", "env", "scripts", "completions", "misc"}
+	if got := cfg.TypeNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("TypeNames = %v, want %v", got, want)
+	}
+
+	env, ok := cfg.LookupType("env")
+	if !ok || env.Description != "environment" || env.Order != 20 || env.Template == "" {
+		t.Errorf("LookupType(env) = %+v, %v", env, ok)
+	}
+	if _, ok := cfg.LookupType("nope"); ok {
+		t.Error("LookupType found a type that is not configured")
+	}
+
+	// The defaults themselves are left alone
+	if DefaultTypes[2].Order != 30 {
+		t.Errorf("DefaultTypes was modified: %+v", DefaultTypes[2])
+	}
+}
+
+func TestConfigTypeHeaders(t *testing.T) {
+	misc := ConfigType{Name: "misc", Template: "# anything"}
+	if got := misc.CoreHeader(); got != "# Global misc\n# anything\n" {
+		t.Errorf("CoreHeader = %q", got)
+	}
+	if got := DefaultTypes[0].ProjectHeader("flutter"); got != "# flutter aliases\n" {
+		t.Errorf("ProjectHeader = %q", got)
+	}
+}
+
+func TestLoadRejectsInvalidTypes(t *testing.T) {
+	for _, types := range []string{
+		"types:\n  - name: ../escape\n",
+		"types:\n  - name: \"\"\n",
+		"types:\n  - name: misc\n  - name: misc\n",
+	} {
+		t.Setenv("HOME", t.TempDir())
+		if err := os.MkdirAll(GetConfigDir(), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(GetConfigPath(), []byte(types), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "config type") {
+			t.Errorf("Load of %q: %v", types, err)
+		}
+	}
+}
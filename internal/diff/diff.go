@@ -0,0 +1,238 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	ConflictStart = "<<<<<<<"
+	ConflictSep   = "======="
+	ConflictEnd   = ">>>>>>>"
+)
+
+// SplitLines splits s into lines that keep their trailing newline, so that
+// joining the result reproduces s exactly.
+func SplitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// match returns, for every line of a, the index of the line in b it is
+// paired with by a longest common subsequence, or -1 when it has no partner.
+func match(a, b []string) []int {
+	n, m := len(a), len(b)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case strings.TrimSuffix(a[i], "\n") == strings.TrimSuffix(b[j], "\n"):
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	pairs := make([]int, n)
+	for i := range pairs {
+		pairs[i] = -1
+	}
+
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case strings.TrimSuffix(a[i], "\n") == strings.TrimSuffix(b[j], "\n"):
+			pairs[i] = j
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+
+	return pairs
+}
+
+// Merge3 performs a line-based three-way merge of ours and theirs against
+// their common ancestor base. Hunks changed on only one side are taken from
+// that side; hunks changed differently on both sides are written out between
+// git-style conflict markers labelled with oursLabel and theirsLabel.
+func Merge3(base, ours, theirs, oursLabel, theirsLabel string) (merged string, conflict bool) {
+	baseLines := SplitLines(base)
+	oursLines := SplitLines(ours)
+	theirsLines := SplitLines(theirs)
+
+	toOurs := match(baseLines, oursLines)
+	toTheirs := match(baseLines, theirsLines)
+
+	var out strings.Builder
+	i, j, k := 0, 0, 0
+
+	for i < len(baseLines) || j < len(oursLines) || k < len(theirsLines) {
+		if i < len(baseLines) && toOurs[i] == j && toTheirs[i] == k {
+			out.WriteString(oursLines[j])
+			i++
+			j++
+			k++
+			continue
+		}
+
+		// Find the next base line that both sides kept
+		nextI, nextJ, nextK := len(baseLines), len(oursLines), len(theirsLines)
+		for x := i; x < len(baseLines); x++ {
+			if toOurs[x] >= 0 && toTheirs[x] >= 0 {
+				nextI, nextJ, nextK = x, toOurs[x], toTheirs[x]
+				break
+			}
+		}
+
+		baseChunk := strings.Join(baseLines[i:nextI], "")
+		oursChunk := strings.Join(oursLines[j:nextJ], "")
+		theirsChunk := strings.Join(theirsLines[k:nextK], "")
+
+		switch {
+		case oursChunk == baseChunk:
+			out.WriteString(theirsChunk)
+		case theirsChunk == baseChunk, oursChunk == theirsChunk:
+			out.WriteString(oursChunk)
+		default:
+			conflict = true
+			out.WriteString(ConflictStart + " " + oursLabel + "\n")
+			out.WriteString(withNewline(oursChunk))
+			out.WriteString(ConflictSep + "\n")
+			out.WriteString(withNewline(theirsChunk))
+			out.WriteString(ConflictEnd + " " + theirsLabel + "\n")
+		}
+
+		i, j, k = nextI, nextJ, nextK
+	}
+
+	return out.String(), conflict
+}
+
+// HasConflictMarkers reports whether content still contains unresolved
+// conflict markers written by Merge3.
+func HasConflictMarkers(content string) bool {
+	for _, line := range strings.Split(content, "\n") {
+		if strings.HasPrefix(line, ConflictStart+" ") || line == ConflictSep || strings.HasPrefix(line, ConflictEnd+" ") {
+			return true
+		}
+	}
+	return false
+}
+
+func withNewline(s string) string {
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		return s + "\n"
+	}
+	return s
+}
+
+const unifiedContext = 3
+
+type diffLine struct {
+	kind byte
+	text string
+}
+
+// Unified returns a unified diff from a to b with three lines of context,
+// or an empty string when they are equal.
+func Unified(a, b, aLabel, bLabel string) string {
+	aLines := SplitLines(a)
+	bLines := SplitLines(b)
+	pairs := match(aLines, bLines)
+
+	var lines []diffLine
+	j := 0
+	for i, line := range aLines {
+		if pairs[i] == -1 {
+			lines = append(lines, diffLine{'-', line})
+			continue
+		}
+		for ; j < pairs[i]; j++ {
+			lines = append(lines, diffLine{'+', bLines[j]})
+		}
+		lines = append(lines, diffLine{' ', line})
+		j++
+	}
+	for ; j < len(bLines); j++ {
+		lines = append(lines, diffLine{'+', bLines[j]})
+	}
+
+	// aStart[x] and bStart[x] are the 1-based line numbers in a and b at
+	// which lines[x] sits
+	aStart := make([]int, len(lines)+1)
+	bStart := make([]int, len(lines)+1)
+	aStart[0], bStart[0] = 1, 1
+	for x, line := range lines {
+		aStart[x+1], bStart[x+1] = aStart[x], bStart[x]
+		if line.kind != '+' {
+			aStart[x+1]++
+		}
+		if line.kind != '-' {
+			bStart[x+1]++
+		}
+	}
+
+	var out strings.Builder
+	for x := 0; x < len(lines); {
+		if lines[x].kind == ' ' {
+			x++
+			continue
+		}
+
+		start := max(x-unifiedContext, 0)
+		end := x
+		for end < len(lines) {
+			if lines[end].kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(lines) && lines[next].kind == ' ' {
+				next++
+			}
+			if next == len(lines) || next-end > 2*unifiedContext {
+				end = min(end+unifiedContext, len(lines))
+				break
+			}
+			end = next
+		}
+
+		if out.Len() == 0 {
+			out.WriteString("--- " + aLabel + "\n+++ " + bLabel + "\n")
+		}
+		out.WriteString(fmt.Sprintf("@@ -%s +%s @@\n",
+			hunkRange(aStart[start], aStart[end]-aStart[start]),
+			hunkRange(bStart[start], bStart[end]-bStart[start])))
+		for _, line := range lines[start:end] {
+			out.WriteString(string(line.kind) + withNewline(line.text))
+		}
+
+		x = end
+	}
+
+	return out.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
@@ -0,0 +1,181 @@
+package diff
+
+import "testing"
+
+func TestSplitLines(t *testing.T) {
+	tests := map[string][]string{
+		"":         nil,
+		"a":        {"a"},
+		"a\n":      {"a\n"},
+		"a\nb":     {"a\n", "b"},
+		"a\n\nb\n": {"a\n", "\n", "b\n"},
+	}
+
+	for in, want := range tests {
+		got := SplitLines(in)
+		if len(got) != len(want) {
+			t.Errorf("SplitLines(%q) = %q, want %q", in, got, want)
+			continue
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("SplitLines(%q) = %q, want %q", in, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestMerge3(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs string
+		want               string
+		conflict           bool
+	}{
+		{
+			name:   "unchanged",
+			base:   "a\nb\n",
+			ours:   "a\nb\n",
+			theirs: "a\nb\n",
+			want:   "a\nb\n",
+		},
+		{
+			name:   "only ours changed",
+			base:   "a\nb\nc\n",
+			ours:   "a\nB\nc\n",
+			theirs: "a\nb\nc\n",
+			want:   "a\nB\nc\n",
+		},
+		{
+			name:   "only theirs changed",
+			base:   "a\nb\nc\n",
+			ours:   "a\nb\nc\n",
+			theirs: "a\nb\nC\n",
+			want:   "a\nb\nC\n",
+		},
+		{
+			name:   "separate hunks",
+			base:   "a\nb\nc\nd\ne\n",
+			ours:   "A\nb\nc\nd\ne\n",
+			theirs: "a\nb\nc\nd\nE\n",
+			want:   "A\nb\nc\nd\nE\n",
+		},
+		{
+			name:   "insertions at both ends",
+			base:   "b\n",
+			ours:   "a\nb\n",
+			theirs: "b\nc\n",
+			want:   "a\nb\nc\n",
+		},
+		{
+			name:   "same change on both sides",
+			base:   "a\nb\nc\n",
+			ours:   "a\nX\nc\n",
+			theirs: "a\nX\nc\n",
+			want:   "a\nX\nc\n",
+		},
+		{
+			name:   "deletion on one side",
+			base:   "a\nb\nc\n",
+			ours:   "a\nc\n",
+			theirs: "a\nb\nc\nd\n",
+			want:   "a\nc\nd\n",
+		},
+		{
+			name:     "conflicting changes",
+			base:     "a\nb\nc\n",
+			ours:     "a\nours\nc\n",
+			theirs:   "a\ntheirs\nc\n",
+			want:     "a\n<<<<<<< local\nours\n=======\ntheirs\n>>>>>>> remote\nc\n",
+			conflict: true,
+		},
+		{
+			name:     "conflict without trailing newline",
+			base:     "a",
+			ours:     "b",
+			theirs:   "c",
+			want:     "<<<<<<< local\nb\n=======\nc\n>>>>>>> remote\n",
+			conflict: true,
+		},
+		{
+			name:     "both sides added a file",
+			base:     "",
+			ours:     "x\n",
+			theirs:   "y\n",
+			want:     "<<<<<<< local\nx\n=======\ny\n>>>>>>> remote\n",
+			conflict: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, conflict := Merge3(tt.base, tt.ours, tt.theirs, "local", "remote")
+			if got != tt.want || conflict != tt.conflict {
+				t.Errorf("Merge3 = %q, %v, want %q, %v", got, conflict, tt.want, tt.conflict)
+			}
+			if HasConflictMarkers(got) != tt.conflict {
+				t.Errorf("HasConflictMarkers(%q) = %v", got, !tt.conflict)
+			}
+		})
+	}
+}
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "equal",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		{
+			name: "changed line with context",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:    "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			want: "--- a\n+++ b\n@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name: "close changes share a hunk",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n",
+			b:    "one\n2\n3\n4\n5\n6\n7\neight\n",
+			want: "--- a\n+++ b\n@@ -1,8 +1,8 @@\n-1\n+one\n 2\n 3\n 4\n 5\n 6\n 7\n-8\n+eight\n",
+		},
+		{
+			name: "distant changes get separate hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n",
+			b:    "one\n2\n3\n4\n5\n6\n7\n8\n9\nten\n",
+			want: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -7,4 +7,4 @@\n 7\n 8\n 9\n-10\n+ten\n",
+		},
+		{
+			name: "insertion into an empty file",
+			a:    "",
+			b:    "a\n",
+			want: "--- a\n+++ b\n@@ -0,0 +1 @@\n+a\n",
+		},
+		{
+			name: "deletion of everything",
+			a:    "a\nb\n",
+			b:    "",
+			want: "--- a\n+++ b\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name: "missing trailing newline",
+			a:    "a\nb",
+			b:    "a\nc",
+			want: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified(tt.a, tt.b, "a", "b"); got != tt.want {
+				t.Errorf("Unified =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,18 +2,82 @@ package git
 
 import (
 	"dotwaifu/internal/config"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
 	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	"time"
 )
 
+const (
+	RemoteName         = "origin"
+	stateIgnorePattern = "/state/"
+)
+
 func InitRepository() error {
 	configDir := config.GetConfigDir()
-	_, err := git.PlainInit(configDir, false)
+	if _, err := git.PlainInit(configDir, false); err != nil {
+		return err
+	}
+	return EnsureIgnoreFile()
+}
+
+// EnsureIgnoreFile makes sure machine-local state never ends up in commits.
+func EnsureIgnoreFile() error {
+	ignorePath := filepath.Join(config.GetConfigDir(), ".gitignore")
+	content, err := os.ReadFile(ignorePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.TrimSpace(line) == stateIgnorePattern {
+			return nil
+		}
+	}
+
+	if len(content) > 0 && !strings.HasSuffix(string(content), "\n") {
+		content = append(content, '\n')
+	}
+	content = append(content, stateIgnorePattern+"\n"...)
+
+	return os.WriteFile(ignorePath, content, 0644)
+}
+
+func Clone(url string) error {
+	configDir := config.GetConfigDir()
+	_, err := git.PlainClone(configDir, false, &git.CloneOptions{
+		URL: NormalizeRepoURL(url),
+	})
 	return err
 }
 
+// NormalizeRepoURL expands GitHub shorthand ("user/repo") into a full clone
+// URL and leaves anything that already looks like a URL or path untouched.
+func NormalizeRepoURL(repo string) string {
+	if strings.Contains(repo, "://") || strings.HasPrefix(repo, "git@") {
+		return repo
+	}
+
+	if strings.HasPrefix(repo, "/") || strings.HasPrefix(repo, ".") || strings.HasPrefix(repo, "~") {
+		return repo
+	}
+
+	parts := strings.Split(strings.TrimPrefix(repo, "github.com/"), "/")
+	if len(parts) == 2 && parts[0] != "" && parts[1] != "" {
+		return "https://github.com/" + parts[0] + "/" + strings.TrimSuffix(parts[1], ".git") + ".git"
+	}
+
+	return repo
+}
+
 func IsGitRepository() bool {
 	configDir := config.GetConfigDir()
 	_, err := git.PlainOpen(configDir)
@@ -21,12 +85,17 @@ func IsGitRepository() bool {
 }
 
 func AddAndCommit(message string) error {
-	configDir := config.GetConfigDir()
-	repo, err := git.PlainOpen(configDir)
+	repo, err := openRepository()
 	if err != nil {
 		return err
 	}
 
+	return commitAll(repo, message)
+}
+
+// commitAll stages every change in the worktree and commits it. When parents
+// are given they replace the default single parent (HEAD).
+func commitAll(repo *git.Repository, message string, parents ...plumbing.Hash) error {
 	worktree, err := repo.Worktree()
 	if err != nil {
 		return err
@@ -43,6 +112,7 @@ func AddAndCommit(message string) error {
 			Email: "dotwaifu@local",
 			When:  time.Now(),
 		},
+		Parents: parents,
 	})
 
 	return err
@@ -61,4 +131,277 @@ func GetStatus() (git.Status, error) {
 	}
 
 	return worktree.Status()
-}
\ No newline at end of file
+}
+
+func openRepository() (*git.Repository, error) {
+	return git.PlainOpen(config.GetConfigDir())
+}
+
+// GetRemoteURL returns the URL of the sync remote, or an empty string when
+// none is configured.
+func GetRemoteURL() (string, error) {
+	repo, err := openRepository()
+	if err != nil {
+		return "", err
+	}
+
+	remote, err := repo.Remote(RemoteName)
+	if errors.Is(err, git.ErrRemoteNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	urls := remote.Config().URLs
+	if len(urls) == 0 {
+		return "", nil
+	}
+	return urls[0], nil
+}
+
+// SetRemote points the sync remote at url, creating it if necessary.
+func SetRemote(url string) error {
+	repo, err := openRepository()
+	if err != nil {
+		return err
+	}
+
+	if err := repo.DeleteRemote(RemoteName); err != nil && !errors.Is(err, git.ErrRemoteNotFound) {
+		return err
+	}
+
+	_, err = repo.CreateRemote(&gitconfig.RemoteConfig{
+		Name: RemoteName,
+		URLs: []string{NormalizeRepoURL(url)},
+	})
+	return err
+}
+
+func Fetch() error {
+	repo, err := openRepository()
+	if err != nil {
+		return err
+	}
+
+	err = repo.Fetch(&git.FetchOptions{RemoteName: RemoteName})
+	if errors.Is(err, git.NoErrAlreadyUpToDate) || errors.Is(err, transport.ErrEmptyRemoteRepository) {
+		return nil
+	}
+	return err
+}
+
+// currentBranch returns the branch HEAD points at, even when the branch has
+// no commits yet.
+func currentBranch(repo *git.Repository) (plumbing.ReferenceName, error) {
+	head, err := repo.Storer.Reference(plumbing.HEAD)
+	if err != nil {
+		return "", err
+	}
+
+	if head.Type() != plumbing.SymbolicReference {
+		return "", fmt.Errorf("HEAD is detached; check out a branch before syncing")
+	}
+	return head.Target(), nil
+}
+
+// refHash resolves name to a commit hash, returning the zero hash when the
+// reference does not exist yet.
+func refHash(repo *git.Repository, name plumbing.ReferenceName) (plumbing.Hash, error) {
+	ref, err := repo.Reference(name, true)
+	if errors.Is(err, plumbing.ErrReferenceNotFound) {
+		return plumbing.ZeroHash, nil
+	}
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return ref.Hash(), nil
+}
+
+func localAndRemoteHashes(repo *git.Repository) (local, remote plumbing.Hash, err error) {
+	branch, err := currentBranch(repo)
+	if err != nil {
+		return
+	}
+
+	if local, err = refHash(repo, branch); err != nil {
+		return
+	}
+
+	remoteRef := plumbing.NewRemoteReferenceName(RemoteName, branch.Short())
+	remote, err = refHash(repo, remoteRef)
+	return
+}
+
+func ancestors(repo *git.Repository, from plumbing.Hash) (map[plumbing.Hash]bool, error) {
+	seen := make(map[plumbing.Hash]bool)
+	if from.IsZero() {
+		return seen, nil
+	}
+
+	iter, err := repo.Log(&git.LogOptions{From: from})
+	if err != nil {
+		return nil, err
+	}
+
+	err = iter.ForEach(func(c *object.Commit) error {
+		seen[c.Hash] = true
+		return nil
+	})
+	return seen, err
+}
+
+// AheadBehind reports how many commits the current branch has that its
+// remote-tracking branch lacks, and vice versa. Call Fetch first.
+func AheadBehind() (ahead, behind int, err error) {
+	repo, err := openRepository()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	local, remote, err := localAndRemoteHashes(repo)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	localSet, err := ancestors(repo, local)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	remoteSet, err := ancestors(repo, remote)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for hash := range localSet {
+		if !remoteSet[hash] {
+			ahead++
+		}
+	}
+	for hash := range remoteSet {
+		if !localSet[hash] {
+			behind++
+		}
+	}
+
+	return ahead, behind, nil
+}
+
+// FastForward moves the current branch to its remote-tracking branch. It
+// refuses to run when the histories have diverged or the worktree is dirty.
+func FastForward() error {
+	repo, err := openRepository()
+	if err != nil {
+		return err
+	}
+
+	ahead, behind, err := AheadBehind()
+	if err != nil {
+		return err
+	}
+	if behind == 0 {
+		return nil
+	}
+	if ahead > 0 {
+		return fmt.Errorf("cannot fast-forward: local and remote histories have diverged")
+	}
+
+	_, remote, err := localAndRemoteHashes(repo)
+	if err != nil {
+		return err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	status, err := worktree.Status()
+	if err != nil {
+		return err
+	}
+	if !status.IsClean() {
+		return fmt.Errorf("cannot fast-forward: the worktree has uncommitted changes")
+	}
+
+	return worktree.Reset(&git.ResetOptions{
+		Commit: remote,
+		Mode:   git.MergeReset,
+	})
+}
+
+func Push() error {
+	repo, err := openRepository()
+	if err != nil {
+		return err
+	}
+
+	branch, err := currentBranch(repo)
+	if err != nil {
+		return err
+	}
+
+	refSpec := gitconfig.RefSpec(fmt.Sprintf("%s:%s", branch, branch))
+	err = repo.Push(&git.PushOptions{
+		RemoteName: RemoteName,
+		RefSpecs:   []gitconfig.RefSpec{refSpec},
+	})
+	if errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return nil
+	}
+	return err
+}
+
+// CheckHealth verifies that HEAD, the history behind it and every object of
+// the current tree can be read, and that the worktree status can be computed.
+// A repository without commits is healthy.
+func CheckHealth() error {
+	repo, err := openRepository()
+	if err != nil {
+		return err
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		if errors.Is(err, plumbing.ErrReferenceNotFound) {
+			return nil
+		}
+		return fmt.Errorf("resolving HEAD: %w", err)
+	}
+
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return fmt.Errorf("reading HEAD commit: %w", err)
+	}
+
+	history, err := repo.Log(&git.LogOptions{From: head.Hash()})
+	if err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+	if err := history.ForEach(func(*object.Commit) error { return nil }); err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return fmt.Errorf("reading tree: %w", err)
+	}
+	err = tree.Files().ForEach(func(file *object.File) error {
+		_, err := file.Contents()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("reading files: %w", err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	if _, err := worktree.Status(); err != nil {
+		return fmt.Errorf("reading worktree status: %w", err)
+	}
+
+	return nil
+}
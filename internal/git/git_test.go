@@ -0,0 +1,246 @@
+package git
+
+import (
+	"dotwaifu/internal/config"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// machine is a home directory standing in for one computer that syncs the
+// configuration. use makes the config directory point into it.
+type machine struct {
+	t    *testing.T
+	home string
+}
+
+func newMachine(t *testing.T) machine {
+	t.Helper()
+	return machine{t: t, home: t.TempDir()}
+}
+
+func (m machine) use() {
+	m.t.Setenv("HOME", m.home)
+}
+
+func (m machine) write(path, content string) {
+	m.t.Helper()
+	m.use()
+	fullPath := filepath.Join(config.GetConfigDir(), filepath.FromSlash(path))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		m.t.Fatal(err)
+	}
+	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+		m.t.Fatal(err)
+	}
+}
+
+func (m machine) read(path string) string {
+	m.t.Helper()
+	m.use()
+	content, err := os.ReadFile(filepath.Join(config.GetConfigDir(), filepath.FromSlash(path)))
+	if err != nil {
+		m.t.Fatal(err)
+	}
+	return string(content)
+}
+
+func (m machine) commit(message string) {
+	m.t.Helper()
+	m.use()
+	if err := AddAndCommit(message); err != nil {
+		m.t.Fatalf("AddAndCommit: %v", err)
+	}
+}
+
+func (m machine) push() {
+	m.t.Helper()
+	m.use()
+	if err := Push(); err != nil {
+		m.t.Fatalf("Push: %v", err)
+	}
+}
+
+func (m machine) fetch() {
+	m.t.Helper()
+	m.use()
+	if err := Fetch(); err != nil {
+		m.t.Fatalf("Fetch: %v", err)
+	}
+}
+
+func (m machine) aheadBehind() (int, int) {
+	m.t.Helper()
+	m.use()
+	ahead, behind, err := AheadBehind()
+	if err != nil {
+		m.t.Fatalf("AheadBehind: %v", err)
+	}
+	return ahead, behind
+}
+
+// newRemote creates an empty bare repository to sync through. The file
+// transport runs git-upload-pack and git-receive-pack, so it needs git.
+func newRemote(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found")
+	}
+
+	dir := t.TempDir()
+	if _, err := git.PlainInit(dir, true); err != nil {
+		t.Fatal(err)
+	}
+	return "file://" + dir
+}
+
+// newSyncedPair sets up a first machine that pushed an initial configuration
+// to remote and a second machine that cloned it.
+func newSyncedPair(t *testing.T, remote string) (first, second machine) {
+	t.Helper()
+
+	first = newMachine(t)
+	first.use()
+	if err := InitRepository(); err != nil {
+		t.Fatal(err)
+	}
+	first.write("shell/shared/core/aliases.sh", "alias g='git'\n")
+	first.write("shell/shared/core/env.sh", "export EDITOR=\"vim\"\n")
+	first.commit("Initial configuration")
+	first.use()
+	if err := SetRemote(remote); err != nil {
+		t.Fatal(err)
+	}
+	first.push()
+
+	second = newMachine(t)
+	second.use()
+	if err := Clone(remote); err != nil {
+		t.Fatalf("Clone: %v", err)
+	}
+	return first, second
+}
+
+func TestNormalizeRepoURL(t *testing.T) {
+	tests := map[string]string{
+		"me/dots":                        "https://github.com/me/dots.git",
+		"me/dots.git":                    "https://github.com/me/dots.git",
+		"github.com/me/dots":             "https://github.com/me/dots.git",
+		"https://gitlab.com/me/dots.git": "https://gitlab.com/me/dots.git",
+		"git@github.com:me/dots.git":     "git@github.com:me/dots.git",
+		"file:///srv/dots.git":           "file:///srv/dots.git",
+		"/srv/dots.git":                  "/srv/dots.git",
+		"./dots":                         "./dots",
+		"dots":                           "dots",
+	}
+
+	for repo, want := range tests {
+		if got := NormalizeRepoURL(repo); got != want {
+			t.Errorf("NormalizeRepoURL(%q) = %q, want %q", repo, got, want)
+		}
+	}
+}
+
+func TestEnsureIgnoreFile(t *testing.T) {
+	m := newMachine(t)
+	m.write(".gitignore", "*.swp")
+	m.use()
+
+	for i := 0; i < 2; i++ {
+		if err := EnsureIgnoreFile(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := m.read(".gitignore"); got != "*.swp\n/state/\n" {
+		t.Errorf(".gitignore = %q", got)
+	}
+}
+
+func TestCloneAndPush(t *testing.T) {
+	remote := newRemote(t)
+	_, second := newSyncedPair(t, remote)
+
+	if got := second.read("shell/shared/core/aliases.sh"); got != "alias g='git'\n" {
+		t.Errorf("cloned aliases.sh = %q", got)
+	}
+
+	second.use()
+	url, err := GetRemoteURL()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if url != remote {
+		t.Errorf("GetRemoteURL = %q, want %q", url, remote)
+	}
+
+	if ahead, behind := second.aheadBehind(); ahead != 0 || behind != 0 {
+		t.Errorf("fresh clone is %d ahead, %d behind", ahead, behind)
+	}
+}
+
+func TestFastForward(t *testing.T) {
+	first, second := newSyncedPair(t, newRemote(t))
+
+	second.write("shell/shared/core/aliases.sh", "alias g='git'\nalias k='kubectl'\n")
+	second.commit("Add k")
+	second.push()
+
+	first.fetch()
+	if ahead, behind := first.aheadBehind(); ahead != 0 || behind != 1 {
+		t.Fatalf("first is %d ahead, %d behind, want 0 and 1", ahead, behind)
+	}
+
+	first.use()
+	if err := FastForward(); err != nil {
+		t.Fatalf("FastForward: %v", err)
+	}
+	if got := first.read("shell/shared/core/aliases.sh"); !strings.Contains(got, "alias k='kubectl'") {
+		t.Errorf("aliases.sh after fast-forward = %q", got)
+	}
+	if ahead, behind := first.aheadBehind(); ahead != 0 || behind != 0 {
+		t.Errorf("after fast-forward first is %d ahead, %d behind", ahead, behind)
+	}
+}
+
+func TestFastForwardRefusesDirtyWorktree(t *testing.T) {
+	first, second := newSyncedPair(t, newRemote(t))
+
+	second.write("shell/shared/core/env.sh", "export EDITOR=\"nvim\"\n")
+	second.commit("Switch to nvim")
+	second.push()
+
+	first.write("shell/shared/core/env.sh", "export EDITOR=\"nano\"\n")
+	first.fetch()
+	first.use()
+	if err := FastForward(); err == nil {
+		t.Fatal("FastForward succeeded with uncommitted changes")
+	}
+	if got := first.read("shell/shared/core/env.sh"); got != "export EDITOR=\"nano\"\n" {
+		t.Errorf("uncommitted change was overwritten: %q", got)
+	}
+}
+
+func TestFastForwardRefusesDivergedHistory(t *testing.T) {
+	first, second := newSyncedPair(t, newRemote(t))
+
+	second.write("shell/shared/core/env.sh", "export EDITOR=\"nvim\"\n")
+	second.commit("Switch to nvim")
+	second.push()
+
+	first.write("shell/shared/core/aliases.sh", "alias g='git'\nalias l='ls'\n")
+	first.commit("Add l")
+	first.fetch()
+	if ahead, behind := first.aheadBehind(); ahead != 1 || behind != 1 {
+		t.Fatalf("first is %d ahead, %d behind, want 1 and 1", ahead, behind)
+	}
+
+	first.use()
+	if err := FastForward(); err == nil {
+		t.Fatal("FastForward succeeded on diverged histories")
+	}
+}
@@ -0,0 +1,364 @@
+package git
+
+import (
+	"dotwaifu/internal/config"
+	"dotwaifu/internal/diff"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+const (
+	Ours   = "ours"
+	Theirs = "theirs"
+)
+
+// MergeResult describes the outcome of merging the remote-tracking branch
+// into the current branch.
+type MergeResult struct {
+	Updated   []string
+	Conflicts []string
+}
+
+func mergeHeadPath() string {
+	return filepath.Join(config.GetStateDir(), "MERGE_HEAD")
+}
+
+func conflictsDir() string {
+	return filepath.Join(config.GetStateDir(), "conflicts")
+}
+
+// ConflictMarkerPath returns the state file whose presence tells the loader
+// not to source path (relative to the config directory).
+func ConflictMarkerPath(path string) string {
+	return filepath.Join(conflictsDir(), filepath.FromSlash(path))
+}
+
+func MergeInProgress() bool {
+	_, err := os.Stat(mergeHeadPath())
+	return err == nil
+}
+
+func commitFiles(repo *git.Repository, hash plumbing.Hash) (map[string]string, error) {
+	files := make(map[string]string)
+	if hash.IsZero() {
+		return files, nil
+	}
+
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	err = tree.Files().ForEach(func(f *object.File) error {
+		content, err := f.Contents()
+		if err != nil {
+			return err
+		}
+		files[f.Name] = content
+		return nil
+	})
+	return files, err
+}
+
+func mergeBase(repo *git.Repository, local, remote plumbing.Hash) (plumbing.Hash, error) {
+	localCommit, err := repo.CommitObject(local)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	remoteCommit, err := repo.CommitObject(remote)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	bases, err := localCommit.MergeBase(remoteCommit)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	// Unrelated histories merge against an empty base
+	if len(bases) == 0 {
+		return plumbing.ZeroHash, nil
+	}
+	return bases[0].Hash, nil
+}
+
+func writeWorktreeFile(path string, content string, exists bool) error {
+	fullPath := filepath.Join(config.GetConfigDir(), filepath.FromSlash(path))
+	if !exists {
+		if err := os.Remove(fullPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(fullPath, []byte(content), 0644)
+}
+
+// MergeRemote merges the remote-tracking branch into the committed local
+// branch file by file. Files changed on only one side are taken as-is, files
+// changed on both sides get a three-way merge. If every file merges cleanly a
+// merge commit is created; otherwise conflict markers are written, the
+// conflicted files are recorded and the merge is left in progress until
+// CompleteMerge is called.
+func MergeRemote() (*MergeResult, error) {
+	repo, err := openRepository()
+	if err != nil {
+		return nil, err
+	}
+
+	local, remote, err := localAndRemoteHashes(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if local.IsZero() || remote.IsZero() {
+		return nil, fmt.Errorf("nothing to merge")
+	}
+
+	base, err := mergeBase(repo, local, remote)
+	if err != nil {
+		return nil, err
+	}
+
+	baseFiles, err := commitFiles(repo, base)
+	if err != nil {
+		return nil, err
+	}
+
+	oursFiles, err := commitFiles(repo, local)
+	if err != nil {
+		return nil, err
+	}
+
+	theirsFiles, err := commitFiles(repo, remote)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]bool)
+	for _, files := range []map[string]string{baseFiles, oursFiles, theirsFiles} {
+		for path := range files {
+			paths[path] = true
+		}
+	}
+
+	sortedPaths := make([]string, 0, len(paths))
+	for path := range paths {
+		sortedPaths = append(sortedPaths, path)
+	}
+	sort.Strings(sortedPaths)
+
+	result := &MergeResult{}
+	for _, path := range sortedPaths {
+		baseContent, inBase := baseFiles[path]
+		oursContent, inOurs := oursFiles[path]
+		theirsContent, inTheirs := theirsFiles[path]
+
+		switch {
+		case inOurs == inTheirs && oursContent == theirsContent:
+			continue
+		case inTheirs == inBase && theirsContent == baseContent:
+			continue
+		case inOurs == inBase && oursContent == baseContent:
+			if err := writeWorktreeFile(path, theirsContent, inTheirs); err != nil {
+				return nil, err
+			}
+			result.Updated = append(result.Updated, path)
+			continue
+		}
+
+		merged, conflict := diff.Merge3(baseContent, oursContent, theirsContent, "local", "remote")
+		if err := writeWorktreeFile(path, merged, true); err != nil {
+			return nil, err
+		}
+
+		if conflict {
+			result.Conflicts = append(result.Conflicts, path)
+		} else {
+			result.Updated = append(result.Updated, path)
+		}
+	}
+
+	if len(result.Conflicts) == 0 {
+		return result, commitAll(repo, "Merge remote dotwaifu configuration", local, remote)
+	}
+
+	for _, path := range result.Conflicts {
+		if err := markConflicted(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, os.WriteFile(mergeHeadPath(), []byte(remote.String()+"\n"), 0644)
+}
+
+func markConflicted(path string) error {
+	markerPath := ConflictMarkerPath(path)
+	if err := os.MkdirAll(filepath.Dir(markerPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(markerPath, nil, 0644)
+}
+
+func readMergeHead() (plumbing.Hash, error) {
+	content, err := os.ReadFile(mergeHeadPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return plumbing.ZeroHash, errors.New("no merge in progress")
+		}
+		return plumbing.ZeroHash, err
+	}
+	return plumbing.NewHash(strings.TrimSpace(string(content))), nil
+}
+
+// ListConflicts returns the files, relative to the config directory, that
+// still have unresolved conflicts.
+func ListConflicts() ([]string, error) {
+	var conflicts []string
+	err := filepath.Walk(conflictsDir(), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+
+		if !info.IsDir() {
+			rel, err := filepath.Rel(conflictsDir(), path)
+			if err != nil {
+				return err
+			}
+			conflicts = append(conflicts, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	return conflicts, err
+}
+
+func MarkResolved(path string) error {
+	err := os.Remove(ConflictMarkerPath(path))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// ResolveWith resolves a conflicted file by taking the whole file from one
+// side of the merge (Ours or Theirs).
+func ResolveWith(path, side string) error {
+	repo, err := openRepository()
+	if err != nil {
+		return err
+	}
+
+	var hash plumbing.Hash
+	switch side {
+	case Ours:
+		hash, _, err = localAndRemoteHashes(repo)
+	case Theirs:
+		hash, err = readMergeHead()
+	default:
+		err = fmt.Errorf("unknown merge side: %s", side)
+	}
+	if err != nil {
+		return err
+	}
+
+	files, err := commitFiles(repo, hash)
+	if err != nil {
+		return err
+	}
+
+	content, exists := files[path]
+	if err := writeWorktreeFile(path, content, exists); err != nil {
+		return err
+	}
+
+	return MarkResolved(path)
+}
+
+// CompleteMerge records the merge commit once every conflict is resolved.
+func CompleteMerge() error {
+	conflicts, err := ListConflicts()
+	if err != nil {
+		return err
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("%d file(s) still have unresolved conflicts", len(conflicts))
+	}
+
+	repo, err := openRepository()
+	if err != nil {
+		return err
+	}
+
+	local, _, err := localAndRemoteHashes(repo)
+	if err != nil {
+		return err
+	}
+
+	remote, err := readMergeHead()
+	if err != nil {
+		return err
+	}
+
+	if err := commitAll(repo, "Merge remote dotwaifu configuration", local, remote); err != nil {
+		return err
+	}
+
+	return clearMergeState()
+}
+
+// AbortMerge throws away the in-progress merge and restores the last local
+// commit.
+func AbortMerge() error {
+	repo, err := openRepository()
+	if err != nil {
+		return err
+	}
+
+	local, _, err := localAndRemoteHashes(repo)
+	if err != nil {
+		return err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	if err := worktree.Reset(&git.ResetOptions{Commit: local, Mode: git.HardReset}); err != nil {
+		return err
+	}
+
+	return clearMergeState()
+}
+
+func clearMergeState() error {
+	if err := os.RemoveAll(conflictsDir()); err != nil {
+		return err
+	}
+
+	err := os.Remove(mergeHeadPath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package git
+
+import (
+	"dotwaifu/internal/diff"
+	"os"
+	"testing"
+)
+
+// divergeEnv commits a different env.sh on both machines, pushing the second
+// one, so that the first has to merge.
+func divergeEnv(t *testing.T, ours, theirs string) machine {
+	t.Helper()
+	first, second := newSyncedPair(t, newRemote(t))
+
+	second.write("shell/shared/core/env.sh", theirs)
+	second.commit("Remote change")
+	second.push()
+
+	first.write("shell/shared/core/env.sh", ours)
+	first.commit("Local change")
+	first.fetch()
+	first.use()
+	return first
+}
+
+func TestMergeRemoteClean(t *testing.T) {
+	first := divergeEnv(t,
+		"export EDITOR=\"vim\"\nexport PAGER=\"less\"\n",
+		"export LANG=\"C\"\nexport EDITOR=\"vim\"\n")
+
+	result, err := MergeRemote()
+	if err != nil {
+		t.Fatalf("MergeRemote: %v", err)
+	}
+	if len(result.Conflicts) != 0 || len(result.Updated) != 1 {
+		t.Fatalf("result = %+v, want one updated file", result)
+	}
+
+	want := "export LANG=\"C\"\nexport EDITOR=\"vim\"\nexport PAGER=\"less\"\n"
+	if got := first.read("shell/shared/core/env.sh"); got != want {
+		t.Errorf("merged env.sh = %q, want %q", got, want)
+	}
+	if MergeInProgress() {
+		t.Error("clean merge left a merge in progress")
+	}
+
+	repo, err := openRepository()
+	if err != nil {
+		t.Fatal(err)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.NumParents() != 2 {
+		t.Errorf("merge commit has %d parents, want 2", commit.NumParents())
+	}
+	if ahead, behind := first.aheadBehind(); ahead != 2 || behind != 0 {
+		t.Errorf("after merge first is %d ahead, %d behind, want 2 and 0", ahead, behind)
+	}
+}
+
+func TestMergeRemoteConflict(t *testing.T) {
+	first := divergeEnv(t, "export EDITOR=\"nano\"\n", "export EDITOR=\"nvim\"\n")
+
+	result, err := MergeRemote()
+	if err != nil {
+		t.Fatalf("MergeRemote: %v", err)
+	}
+	if len(result.Conflicts) != 1 || result.Conflicts[0] != "shell/shared/core/env.sh" {
+		t.Fatalf("conflicts = %v", result.Conflicts)
+	}
+	if !MergeInProgress() {
+		t.Fatal("no merge in progress after a conflict")
+	}
+	if !diff.HasConflictMarkers(first.read("shell/shared/core/env.sh")) {
+		t.Error("conflicted env.sh has no conflict markers")
+	}
+	if _, err := os.Stat(ConflictMarkerPath("shell/shared/core/env.sh")); err != nil {
+		t.Errorf("no conflict marker for the loader: %v", err)
+	}
+
+	if err := CompleteMerge(); err == nil {
+		t.Fatal("CompleteMerge succeeded with an unresolved conflict")
+	}
+
+	if err := ResolveWith("shell/shared/core/env.sh", Theirs); err != nil {
+		t.Fatalf("ResolveWith: %v", err)
+	}
+	if got := first.read("shell/shared/core/env.sh"); got != "export EDITOR=\"nvim\"\n" {
+		t.Errorf("env.sh resolved with theirs = %q", got)
+	}
+	if conflicts, err := ListConflicts(); err != nil || len(conflicts) != 0 {
+		t.Errorf("ListConflicts = %v, %v after resolving", conflicts, err)
+	}
+
+	first.use()
+	if err := CompleteMerge(); err != nil {
+		t.Fatalf("CompleteMerge: %v", err)
+	}
+	if MergeInProgress() {
+		t.Error("merge still in progress after CompleteMerge")
+	}
+	if ahead, behind := first.aheadBehind(); ahead != 2 || behind != 0 {
+		t.Errorf("after merge first is %d ahead, %d behind, want 2 and 0", ahead, behind)
+	}
+}
+
+func TestAbortMerge(t *testing.T) {
+	first := divergeEnv(t, "export EDITOR=\"nano\"\n", "export EDITOR=\"nvim\"\n")
+
+	if _, err := MergeRemote(); err != nil {
+		t.Fatalf("MergeRemote: %v", err)
+	}
+	if err := AbortMerge(); err != nil {
+		t.Fatalf("AbortMerge: %v", err)
+	}
+
+	if got := first.read("shell/shared/core/env.sh"); got != "export EDITOR=\"nano\"\n" {
+		t.Errorf("env.sh after abort = %q", got)
+	}
+	if MergeInProgress() {
+		t.Error("merge still in progress after AbortMerge")
+	}
+	if conflicts, err := ListConflicts(); err != nil || len(conflicts) != 0 {
+		t.Errorf("ListConflicts = %v, %v after abort", conflicts, err)
+	}
+}
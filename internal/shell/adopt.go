@@ -0,0 +1,133 @@
+package shell
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+// AdoptItem is a snippet that was added to the RC file outside the dotwaifu
+// block, together with the module it should move to. An empty Project means
+// a core module.
+type AdoptItem struct {
+	ImportItem
+	Project string
+}
+
+// Target returns the module path relative to shell/shared.
+func (a AdoptItem) Target() string {
+	return Module{Project: a.Project, Type: a.Category}.RelPath()
+}
+
+// adoptKeywords maps words that installers leave in their snippets to the
+// project they belong to. Existing projects are matched by name first.
+var adoptKeywords = []struct {
+	keyword string
+	project string
+}{
+	{"flutter", "flutter"},
+	{"dart", "flutter"},
+	{"nvm", "node"},
+	{"fnm", "node"},
+	{"volta", "node"},
+	{"pnpm", "node"},
+	{"npm", "node"},
+	{"conda", "python"},
+	{"mamba", "python"},
+	{"pyenv", "python"},
+	{"poetry", "python"},
+	{"rustup", "rust"},
+	{"cargo", "rust"},
+	{"sdkman", "java"},
+	{"jenv", "java"},
+	{"rbenv", "ruby"},
+	{"rvm", "ruby"},
+	{"goenv", "go"},
+	{"gvm", "go"},
+	{"android", "android"},
+	{"bun", "bun"},
+	{"deno", "deno"},
+}
+
+// mentions reports whether text contains word, delimited by anything other
+// than letters and digits so that NVM_DIR and .nvm/ both match nvm.
+func mentions(text, word string) bool {
+	pattern := `(?i)(^|[^a-z0-9])` + regexp.QuoteMeta(word) + `([^a-z0-9]|$)`
+	return regexp.MustCompile(pattern).MatchString(text)
+}
+
+// SuggestProject picks the project a snippet most likely belongs to, or ""
+// for core.
+func SuggestProject(lines []string) string {
+	text := strings.Join(lines, "\n")
+
+	projects, _ := ListProjects()
+	for _, project := range projects {
+		if mentions(text, project) {
+			return project
+		}
+	}
+
+	for _, entry := range adoptKeywords {
+		if mentions(text, entry.keyword) {
+			return entry.project
+		}
+	}
+
+	return ""
+}
+
+// FindAdoptable returns the snippets outside the dotwaifu block of the RC
+// file that are neither in the backup taken at init nor part of a generated
+// RC file. Statements that directly follow each other are kept together, as
+// installers usually write several related lines at once.
+func FindAdoptable(shell string) ([]AdoptItem, error) {
+	content, err := os.ReadFile(GetRCFilePath(shell))
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool)
+	baseline, err := GenerateRCContent(shell, false)
+	if err != nil {
+		return nil, err
+	}
+	if backup, err := os.ReadFile(GetBackupPath(shell)); err == nil {
+		baseline += "\n" + string(backup)
+	}
+	for _, line := range strings.Split(baseline, "\n") {
+		known[strings.TrimSpace(line)] = true
+	}
+
+	isNew := func(item ImportItem) bool {
+		for _, line := range item.Lines {
+			trimmed := strings.TrimSpace(line)
+			if trimmed != "" && !known[trimmed] {
+				return true
+			}
+		}
+		return false
+	}
+
+	var snippets []ImportItem
+	for _, item := range ClassifyRC(string(content)) {
+		if !isNew(item) {
+			continue
+		}
+
+		if last := len(snippets) - 1; last >= 0 && snippets[last].EndLine+1 == item.StartLine {
+			snippets[last].Lines = append(snippets[last].Lines, item.Lines...)
+			snippets[last].EndLine = item.EndLine
+			continue
+		}
+		snippets = append(snippets, item)
+	}
+
+	items := make([]AdoptItem, 0, len(snippets))
+	for _, snippet := range snippets {
+		snippet.Category = ClassifyStatement(snippet.Lines)
+		items = append(items, AdoptItem{ImportItem: snippet, Project: SuggestProject(snippet.Lines)})
+	}
+
+	return items, nil
+}
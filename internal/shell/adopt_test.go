@@ -0,0 +1,74 @@
+package shell
+
+import (
+	"dotwaifu/internal/config"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSuggestProject(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	if err := os.MkdirAll(filepath.Join(config.GetConfigDir(), "shell", "shared", "projects", "work"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		`export NVM_DIR="$HOME/.nvm"`:          "node",
+		`export PATH="$HOME/.cargo/bin:$PATH"`: "rust",
+		"export KUBECONFIG=~/work/kube":        "work",
+		"source ~/.nvmrc":                      "",
+		"alias g=git":                          "",
+	}
+
+	for line, want := range tests {
+		if got := SuggestProject([]string{line}); got != want {
+			t.Errorf("SuggestProject(%q) = %q, want %q", line, got, want)
+		}
+	}
+}
+
+func TestFindAdoptable(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	rcPath := GetRCFilePath("bash")
+	if err := os.WriteFile(rcPath, []byte("alias ll='ls -la'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := BackupExistingRC("bash"); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendToExistingRC("bash"); err != nil {
+		t.Fatal(err)
+	}
+
+	// What installers typically append after the integration
+	rc, err := os.OpenFile(rcPath, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = rc.WriteString(`
+export NVM_DIR="$HOME/.nvm"
+[ -s "$NVM_DIR/nvm.sh" ] && \. "$NVM_DIR/nvm.sh"
+
+export PATH="$HOME/.cargo/bin:$PATH"
+`)
+	rc.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := FindAdoptable("bash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("got %d items, want 2: %+v", len(items), items)
+	}
+
+	if len(items[0].Lines) != 2 || items[0].Project != "node" || items[0].Target() != "projects/node/scripts.sh" {
+		t.Errorf("first item = %+v, target %s", items[0], items[0].Target())
+	}
+	if len(items[1].Lines) != 1 || items[1].Project != "rust" || items[1].Target() != "projects/rust/paths.sh" {
+		t.Errorf("second item = %+v, target %s", items[1], items[1].Target())
+	}
+}
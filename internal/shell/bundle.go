@@ -0,0 +1,176 @@
+package shell
+
+import (
+	"crypto/sha256"
+	"dotwaifu/internal/config"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var bundleStampPattern = regexp.MustCompile(`(?m)^# dotwaifu bundle sha256:([0-9a-f]+)$`)
+
+// GetBundlePath is the single-file loader written by 'dotwaifu build'. While
+// it exists, the RC block sources it instead of the loader.
+func GetBundlePath() string {
+	return filepath.Join(config.GetStateDir(), "bundle.sh")
+}
+
+// BundleExists reports whether bundle mode is on.
+func BundleExists() bool {
+	_, err := os.Stat(GetBundlePath())
+	return err == nil
+}
+
+// moduleWatchList returns the files and directories whose changes make the
+// loader or the bundle stale, relative to the config directory. Directories
+// are included so that added and removed modules are noticed too.
+func moduleWatchList() ([]string, error) {
+	watched := []string{"config.yaml", "shell/shared/core", "shell/shared/projects"}
+
+	projects, err := ListProjects()
+	if err != nil {
+		return nil, err
+	}
+	for _, project := range projects {
+		watched = append(watched, "shell/shared/projects/"+project)
+		if _, err := os.Stat(GetProjectManifestPath(project)); err == nil {
+			watched = append(watched, "shell/shared/projects/"+project+"/"+ProjectManifestName)
+		}
+	}
+
+	modules, err := ListModules()
+	if err != nil {
+		return nil, err
+	}
+	for _, module := range modules {
+		watched = append(watched, "shell/shared/"+module.RelPath())
+	}
+
+	return watched, nil
+}
+
+// freshnessCheck runs before the modules of state/<name>.sh. When one of the
+// watched files is newer than the script, rebuild regenerates it and it is
+// sourced again, or a warning with advice is printed if that is not
+// possible.
+func freshnessCheck(name string, watched []string, rebuild, advice string) string {
+	var files []string
+	for _, path := range watched {
+		files = append(files, `"$DOTWAIFU_CONFIG_ROOT"/`+shellQuote(path))
+	}
+
+	return fmt.Sprintf(`# Regenerate when a module changed without going through dotwaifu, e.g.
+# after a git pull; _dotwaifu_%[1]s_checked stops a second round
+if [ -z "$_dotwaifu_%[1]s_checked" ]; then
+    for _dotwaifu_file in %[2]s; do
+        [ "$_dotwaifu_file" -nt "$DOTWAIFU_CONFIG_ROOT/state/%[1]s.sh" ] || continue
+        _dotwaifu_%[1]s_checked=1
+        if command -v dotwaifu >/dev/null 2>&1 && %[3]s; then
+            source "$DOTWAIFU_CONFIG_ROOT/state/%[1]s.sh"
+            unset _dotwaifu_%[1]s_checked _dotwaifu_file
+            return 0
+        fi
+        echo "dotwaifu: ${_dotwaifu_file#"$DOTWAIFU_CONFIG_ROOT"/} %[4]s" >&2
+        break
+    done
+fi
+unset _dotwaifu_%[1]s_checked _dotwaifu_file
+`, name, strings.Join(files, " \\\n        "), rebuild, advice)
+}
+
+func bundleChecksum(body string) string {
+	sum := sha256.Sum256([]byte(body))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// BundleScript compiles every module that is loaded right away into a single
+// script for shell, see LoaderScript. Projects with activation rules are
+// still sourced by their hook when needed.
+func BundleScript(shell string) (string, error) {
+	var body strings.Builder
+	if err := compileLoader(&body, nil, ModuleShell(shell)); err != nil {
+		return "", err
+	}
+
+	watched, err := moduleWatchList()
+	if err != nil {
+		return "", err
+	}
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "# dotwaifu bundle sha256:%s\n", bundleChecksum(body.String()))
+	out.WriteString("# Generated by dotwaifu from ~/.config/dotwaifu/shell/shared - DO NOT EDIT MANUALLY\n")
+	out.WriteString("# 'dotwaifu build' regenerates it, 'dotwaifu build --remove' turns bundling off\n\n")
+	out.WriteString(bundleSafeModeCheck + "\n")
+	out.WriteString(freshnessCheck("bundle", watched, "dotwaifu build --quiet", "changed since the bundle was built, run 'dotwaifu build'"))
+	out.WriteString("\n" + body.String())
+	return out.String(), nil
+}
+
+// BuildBundle writes the bundle and drops any compiled copy of the previous
+// one, see CompileBundle.
+func BuildBundle(shell string) error {
+	script, err := BundleScript(shell)
+	if err != nil {
+		return err
+	}
+
+	bundlePath := GetBundlePath()
+	if err := os.MkdirAll(filepath.Dir(bundlePath), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(bundlePath, []byte(script), 0644); err != nil {
+		return err
+	}
+
+	if err := os.Remove(bundlePath + ".zwc"); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// CompileBundle runs zcompile on the bundle so zsh can load it faster. zsh
+// ignores the compiled file once the bundle is newer, so a failed zcompile
+// only costs speed.
+func CompileBundle() error {
+	output, err := exec.Command("zsh", "-c", `zcompile "$1"`, "zsh", GetBundlePath()).CombinedOutput()
+	if err != nil {
+		if message := strings.TrimSpace(string(output)); message != "" {
+			return fmt.Errorf("zcompile: %s", message)
+		}
+		return fmt.Errorf("zcompile: %w", err)
+	}
+	return nil
+}
+
+// RemoveBundle turns bundle mode off.
+func RemoveBundle() error {
+	for _, path := range []string{GetBundlePath(), GetBundlePath() + ".zwc"} {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// BundleIsCurrent reports whether the bundle still matches the modules, by
+// comparing its checksum with a fresh compile.
+func BundleIsCurrent(shell string) (bool, error) {
+	content, err := os.ReadFile(GetBundlePath())
+	if err != nil {
+		return false, err
+	}
+
+	var body strings.Builder
+	if err := compileLoader(&body, nil, ModuleShell(shell)); err != nil {
+		return false, err
+	}
+
+	match := bundleStampPattern.FindStringSubmatch(string(content))
+	return match != nil && match[1] == bundleChecksum(body.String()), nil
+}
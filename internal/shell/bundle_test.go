@@ -0,0 +1,247 @@
+package shell
+
+import (
+	"dotwaifu/internal/config"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sourceBundle builds the bundle, sources it in a new bash followed by script
+// and returns what that printed on stdout and stderr.
+func sourceBundle(t *testing.T, script string) (stdout, stderr string) {
+	t.Helper()
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	if err := BuildBundle("bash"); err != nil {
+		t.Fatalf("BuildBundle: %v", err)
+	}
+
+	// Aliases are expanded as in an interactive shell
+	cmd := exec.Command("bash", "--norc", "--noprofile", "-c", `shopt -s expand_aliases; source "$DOTWAIFU_CONFIG_ROOT/state/bundle.sh"`+"\n"+script)
+	cmd.Env = append(cmd.Environ(), "DOTWAIFU_CONFIG_ROOT="+config.GetConfigDir(), "PATH=/usr/bin:/bin")
+	var out, errOut strings.Builder
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("bash: %v\n%s", err, errOut.String())
+	}
+	return out.String(), errOut.String()
+}
+
+func TestBundleInlinesModules(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh":             "export EDITOR=vim\n",
+		"core/paths.sh":           "export PATH=\"/opt/bin:$PATH\"",
+		"projects/rust/env.sh":    "export CARGO_HOME=/opt/cargo\n",
+		"projects/flutter/env.sh": "export FLUTTER_ROOT=/opt/flutter\n",
+	})
+	saveManifest(t, "flutter", Activation{Dirs: []string{"~/code/app"}})
+
+	script, err := BundleScript("bash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# --- core/env.sh ---\n",
+		"\nexport EDITOR=vim\n",
+		"# --- core/paths.sh ---\n",
+		"\nexport PATH=\"/opt/bin:$PATH\"\n",
+		"# --- projects/rust/env.sh ---\n",
+		"\nexport CARGO_HOME=/opt/cargo\n",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("bundle does not contain %q", want)
+		}
+	}
+	// Projects with activation rules are still sourced by their hook
+	if strings.Contains(script, "# --- projects/flutter/env.sh ---") {
+		t.Error("a project with activation rules is inlined")
+	}
+
+	stdout, stderr := sourceBundle(t, `mkdir -p "$HOME/code/app"
+echo "$EDITOR $PATH ${CARGO_HOME-unset} ${FLUTTER_ROOT-unset}"
+cd "$HOME/code/app"; _dotwaifu_activate
+echo "${FLUTTER_ROOT-unset}"`)
+	if stdout != "vim /opt/bin:/usr/bin:/bin /opt/cargo unset\n/opt/flutter\n" {
+		t.Errorf("after sourcing the bundle got %q", stdout)
+	}
+	if stderr != "" {
+		t.Errorf("stderr = %q", stderr)
+	}
+}
+
+func TestBundleIsCurrent(t *testing.T) {
+	setupModules(t, map[string]string{"core/env.sh": "export EDITOR=vim\n"})
+	if err := BuildBundle("bash"); err != nil {
+		t.Fatal(err)
+	}
+
+	if current, err := BundleIsCurrent("bash"); err != nil || !current {
+		t.Errorf("BundleIsCurrent after a build = %v, %v", current, err)
+	}
+
+	envPath := filepath.Join(GetCoreDir(), "env.sh")
+	if err := os.WriteFile(envPath, []byte("export EDITOR=nvim\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if current, err := BundleIsCurrent("bash"); err != nil || current {
+		t.Errorf("BundleIsCurrent after a change = %v, %v", current, err)
+	}
+
+	if err := RemoveBundle(); err != nil {
+		t.Fatal(err)
+	}
+	if BundleExists() {
+		t.Error("bundle still exists after RemoveBundle")
+	}
+}
+
+func TestBundleWarnsWhenStale(t *testing.T) {
+	setupModules(t, map[string]string{"core/env.sh": "export EDITOR=vim\n"})
+
+	// A module that changed behind dotwaifu's back, e.g. by a git pull.
+	// dotwaifu is not on the PATH, so the bundle cannot rebuild itself.
+	later := time.Now().Add(time.Hour)
+	_, stderr := sourceBundle(t, `touch -d "`+later.Format(time.RFC3339)+`" "$DOTWAIFU_CONFIG_ROOT/shell/shared/core/env.sh"
+source "$DOTWAIFU_CONFIG_ROOT/state/bundle.sh"`)
+
+	want := "dotwaifu: shell/shared/core/env.sh changed since the bundle was built, run 'dotwaifu build'\n"
+	if stderr != want {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+}
+
+func TestHasTopLevelReturn(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"export A=1\n", false},
+		{"[ -n \"$X\" ] || return 0\n", true},
+		{"command -v nvm >/dev/null || return\n", true},
+		{"if [ -z \"$GOPATH\" ]; then\n    return 0\nfi\n", true},
+		{"{ return; }\n", true},
+		{"mkcd() {\n    mkdir -p \"$1\" || return 1\n    cd \"$1\"\n}\n", false},
+		{"mkcd () { mkdir -p \"$1\" && cd \"$1\" || return; }\n", false},
+		{"function up {\n    for i in $(seq \"$1\"); do cd ..; done\n    return 0\n}\n", false},
+		{"function up() { cd ..; return; }\n", false},
+		{"outer() {\n    inner() { return 1; }\n    { return 0; }\n}\n", false},
+		{"f() { :; }\nreturn\n", true},
+		{"echo 'return' \"return\" # return\n", false},
+		{"echo \"}\"\n", false},
+		{"}\n", true},
+		{"f() {\n", true},
+		{"(return 0)\n", true},
+	}
+
+	for _, tt := range tests {
+		if got := hasTopLevelReturn(tt.content); got != tt.want {
+			t.Errorf("hasTopLevelReturn(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestBundleSourcesModulesThatReturnEarly(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh":   "[ -n \"$NOT_SET\" ] || return 0\nexport SKIPPED=1\n",
+		"core/paths.sh": "export PATH=\"/opt/bin:$PATH\"\nexport LATER=1\n",
+	})
+
+	script, err := BundleScript("bash")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(script, "# --- core/env.sh (sourced, it may return early) ---") {
+		t.Error("core/env.sh is inlined although it may return early")
+	}
+	if !strings.Contains(script, "export LATER=1") {
+		t.Error("core/paths.sh is not inlined")
+	}
+
+	stdout, _ := sourceBundle(t, `echo "${SKIPPED:-unset} $LATER"`)
+	if stdout != "unset 1\n" {
+		t.Errorf("after sourcing the bundle got %q, want %q", stdout, "unset 1\n")
+	}
+}
+
+func TestUsesOwnAliases(t *testing.T) {
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"alias g=git\nalias ll='ls -la'\nexport A=1\n", false},
+		{"mkcd() { mkdir -p \"$1\"; }\nalias m=mkcd\n", false},
+		{"alias g=git\ncompdef g=git\n", true},
+		{"alias la='ls -a'; alias l=la\nl\n", true},
+		{"# alias g=git\nsetopt autocd\n", false},
+	}
+
+	for _, tt := range tests {
+		if got := usesOwnAliases(tt.content); got != tt.want {
+			t.Errorf("usesOwnAliases(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestBundleKeepsStderrOfLaterCode(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh":     "export BEFORE=1\ndotwaifu_no_such_command\n",
+		"core/aliases.sh": "alias say=echo\nsay_hi() { say hi; }\nexport GREETING=\"$(say_hi)\"\n",
+		"core/scripts.sh": "echo 'a warning' >&2\nexport SCRIPTS=1\n",
+	})
+
+	stdout, stderr := sourceBundle(t, `echo "$BEFORE $GREETING $SCRIPTS"; echo after >&2`)
+	if stdout != "1 hi 1\n" {
+		t.Errorf("after sourcing the bundle got %q", stdout)
+	}
+	for _, want := range []string{"a warning\n", "after\n"} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("stderr %q does not contain %q", stderr, want)
+		}
+	}
+}
+
+func TestBundleIsolatesFailingModules(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh":     "export BEFORE=1\ndotwaifu_no_such_command\n",
+		"core/paths.sh":   "echo 'a warning' >&2\nexport PATHS=1\n",
+		"core/aliases.sh": "alias say=echo\nsay_hi() { say hi; }\nexport GREETING=\"$(say_hi)\"\n",
+		"core/scripts.sh": "if true; then\n",
+	})
+
+	stdout, stderr := sourceBundle(t, `echo "$BEFORE $PATHS $GREETING"; echo after >&2`)
+	if stdout != "1 1 hi\n" {
+		t.Errorf("after sourcing the bundle got %q", stdout)
+	}
+
+	for _, want := range []string{
+		"dotwaifu: core/env.sh failed: line 2: dotwaifu_no_such_command: command not found\n",
+		"dotwaifu: core/scripts.sh failed: line 2: syntax error",
+		"a warning\n",
+		"after\n",
+	} {
+		if !strings.Contains(stderr, want) {
+			t.Errorf("stderr %q does not contain %q", stderr, want)
+		}
+	}
+	if strings.Contains(stderr, "paths.sh failed") {
+		t.Errorf("a warning was reported as a failure: %q", stderr)
+	}
+
+	status, err := os.ReadFile(filepath.Join(config.GetStateDir(), "status"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(status), "core/env.sh\t") || strings.Contains(string(status), "core/paths.sh") {
+		t.Errorf("status = %q", status)
+	}
+
+	leftovers, _ := filepath.Glob(filepath.Join(config.GetStateDir(), "stderr.*"))
+	if len(leftovers) > 0 {
+		t.Errorf("stderr files left behind: %v", leftovers)
+	}
+}
@@ -0,0 +1,146 @@
+package shell
+
+import (
+	"bufio"
+	"dotwaifu/internal/config"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cacheHelper defines dotwaifu_cache_eval for the modules. It replaces
+// eval "$(pyenv init -)" with dotwaifu_cache_eval pyenv init -, which runs the
+// command once and sources its saved output on later starts. The cache is
+// keyed by the shell, the binary's path and the arguments, and is refreshed
+// when the binary is newer than the cached output.
+const cacheHelper = `# Caches the output of setup commands such as 'pyenv init -', see
+# 'dotwaifu cache list'
+dotwaifu_cache_eval() {
+    _dotwaifu_bin=$(command -v "$1" 2>/dev/null)
+    case "$_dotwaifu_bin" in
+        /*) ;;
+        *) echo "dotwaifu: $1: command not found" >&2; return 127 ;;
+    esac
+    _dotwaifu_shell=${ZSH_VERSION:+zsh}${BASH_VERSION:+bash}
+    _dotwaifu_key="$_dotwaifu_shell $_dotwaifu_bin $*"
+    _dotwaifu_cache="$DOTWAIFU_CONFIG_ROOT/state/cache/${_dotwaifu_key//[^A-Za-z0-9._-]/_}.sh"
+    if [ ! -s "$_dotwaifu_cache" ] || [ "$_dotwaifu_bin" -nt "$_dotwaifu_cache" ]; then
+        mkdir -p "$DOTWAIFU_CONFIG_ROOT/state/cache" || return 1
+        {
+            printf '# command: %s\n# binary: %s\n# shell: %s\n' "$*" "$_dotwaifu_bin" "$_dotwaifu_shell"
+            "$@"
+        } >"$_dotwaifu_cache.tmp" || { rm -f "$_dotwaifu_cache.tmp"; return 1; }
+        mv -f "$_dotwaifu_cache.tmp" "$_dotwaifu_cache"
+    fi
+    source "$_dotwaifu_cache"
+}
+`
+
+// GetCacheDir is where dotwaifu_cache_eval keeps command output.
+func GetCacheDir() string {
+	return filepath.Join(config.GetStateDir(), "cache")
+}
+
+// CacheEntry is the saved output of one dotwaifu_cache_eval command.
+type CacheEntry struct {
+	Path    string
+	Command string
+	Binary  string
+	Shell   string
+	Size    int64
+	ModTime time.Time
+}
+
+// Stale reports whether the entry will be refreshed on the next shell start,
+// because its binary changed or is gone.
+func (e CacheEntry) Stale() bool {
+	info, err := os.Stat(e.Binary)
+	return err != nil || info.ModTime().After(e.ModTime)
+}
+
+// Name returns the first word of the command, e.g. "pyenv".
+func (e CacheEntry) Name() string {
+	if fields := strings.Fields(e.Command); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+func readCacheEntry(path string) (CacheEntry, error) {
+	entry := CacheEntry{Path: path}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return entry, err
+	}
+	entry.Size = info.Size()
+	entry.ModTime = info.ModTime()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return entry, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for i := 0; i < 3 && scanner.Scan(); i++ {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "# command: "):
+			entry.Command = strings.TrimPrefix(line, "# command: ")
+		case strings.HasPrefix(line, "# binary: "):
+			entry.Binary = strings.TrimPrefix(line, "# binary: ")
+		case strings.HasPrefix(line, "# shell: "):
+			entry.Shell = strings.TrimPrefix(line, "# shell: ")
+		}
+	}
+	return entry, scanner.Err()
+}
+
+// ListCache returns the cached command outputs sorted by command.
+func ListCache() ([]CacheEntry, error) {
+	matches, err := filepath.Glob(filepath.Join(GetCacheDir(), "*.sh"))
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []CacheEntry
+	for _, match := range matches {
+		entry, err := readCacheEntry(match)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Command != entries[j].Command {
+			return entries[i].Command < entries[j].Command
+		}
+		return entries[i].Shell < entries[j].Shell
+	})
+	return entries, nil
+}
+
+// ClearCache deletes the cached outputs of the commands named name, or all of
+// them if name is empty, and returns how many were deleted.
+func ClearCache(name string) (int, error) {
+	entries, err := ListCache()
+	if err != nil {
+		return 0, err
+	}
+
+	cleared := 0
+	for _, entry := range entries {
+		if name != "" && entry.Name() != name && filepath.Base(entry.Binary) != name {
+			continue
+		}
+		if err := os.Remove(entry.Path); err != nil {
+			return cleared, err
+		}
+		cleared++
+	}
+	return cleared, nil
+}
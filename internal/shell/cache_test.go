@@ -0,0 +1,96 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeInit writes a setup command like 'pyenv init -' to $HOME/bin that
+// records every run in $HOME/runs.
+func fakeInit(t *testing.T) string {
+	t.Helper()
+	home, _ := os.UserHomeDir()
+	path := filepath.Join(home, "bin", "fakeinit")
+	script := "#!/bin/sh\necho run >> \"$HOME/runs\"\necho \"export FAKE_INIT='$*'\"\n"
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func countRuns(t *testing.T) int {
+	t.Helper()
+	home, _ := os.UserHomeDir()
+	content, err := os.ReadFile(filepath.Join(home, "runs"))
+	if err != nil && !os.IsNotExist(err) {
+		t.Fatal(err)
+	}
+	return strings.Count(string(content), "run\n")
+}
+
+func TestCacheEval(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh": "dotwaifu_cache_eval \"$HOME/bin/fakeinit\" init -\n",
+	})
+	binary := fakeInit(t)
+
+	for i := 0; i < 2; i++ {
+		if got := runLoader(t, `echo "$FAKE_INIT"`); got != "init -\n" {
+			t.Fatalf("shell %d got %q", i+1, got)
+		}
+	}
+	if runs := countRuns(t); runs != 1 {
+		t.Errorf("the command ran %d times in two shells, want once", runs)
+	}
+
+	entries, err := ListCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("ListCache = %+v", entries)
+	}
+	entry := entries[0]
+	if entry.Command != binary+" init -" || entry.Binary != binary || entry.Shell != "bash" || entry.Stale() {
+		t.Errorf("cache entry = %+v", entry)
+	}
+
+	// Upgrading the binary invalidates its cached output
+	later := time.Now().Add(time.Hour)
+	if err := os.Chtimes(binary, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := ListCache(); len(entries) != 1 || !entries[0].Stale() {
+		t.Errorf("cache entry after an upgrade = %+v", entries)
+	}
+	runLoader(t, "")
+	if runs := countRuns(t); runs != 2 {
+		t.Errorf("the command ran %d times after an upgrade, want twice", runs)
+	}
+
+	if cleared, err := ClearCache("other"); err != nil || cleared != 0 {
+		t.Errorf("ClearCache(other) = %d, %v", cleared, err)
+	}
+	if cleared, err := ClearCache("fakeinit"); err != nil || cleared != 1 {
+		t.Errorf("ClearCache(fakeinit) = %d, %v", cleared, err)
+	}
+}
+
+func TestCacheEvalMissingCommand(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh": "dotwaifu_cache_eval dotwaifu-no-such-command init - || echo \"status $?\"\n",
+	})
+
+	if got := runLoader(t, ""); got != "status 127\n" {
+		t.Errorf("got %q", got)
+	}
+	if entries, _ := ListCache(); len(entries) != 0 {
+		t.Errorf("a missing command was cached: %+v", entries)
+	}
+}
@@ -1,9 +1,9 @@
 package shell
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 )
 
 func DetectShell() string {
@@ -18,6 +18,12 @@ func DetectShell() string {
 		return "zsh"
 	case "bash":
 		return "bash"
+	case "fish":
+		return "fish"
+	case "nu":
+		return "nu"
+	case "pwsh":
+		return "pwsh"
 	default:
 		return shellName
 	}
@@ -29,6 +35,12 @@ func GetRCFileName(shell string) string {
 		return ".zshrc"
 	case "bash":
 		return ".bashrc"
+	case "fish":
+		return "dotwaifu.fish"
+	case "nu":
+		return "config.nu"
+	case "pwsh":
+		return "Microsoft.PowerShell_profile.ps1"
 	default:
 		return ".shellrc"
 	}
@@ -36,7 +48,28 @@ func GetRCFileName(shell string) string {
 
 func GetRCFilePath(shell string) string {
 	home, _ := os.UserHomeDir()
-	return filepath.Join(home, GetRCFileName(shell))
+	switch shell {
+	case "fish":
+		// fish sources everything in conf.d, so dotwaifu gets its own file
+		return filepath.Join(home, ".config", "fish", "conf.d", GetRCFileName(shell))
+	case "nu":
+		return filepath.Join(GetNushellConfigDir(), GetRCFileName(shell))
+	case "pwsh":
+		// $PROFILE for the current user on Linux and macOS
+		return filepath.Join(home, ".config", "powershell", GetRCFileName(shell))
+	default:
+		return filepath.Join(home, GetRCFileName(shell))
+	}
+}
+
+// GetSourceCommand returns the command that loads path into shell.
+func GetSourceCommand(shell, path string) string {
+	switch shell {
+	case "pwsh":
+		return fmt.Sprintf(". '%s'", path)
+	default:
+		return fmt.Sprintf("source %s", path)
+	}
 }
 
 func HasExistingRC(shell string) bool {
@@ -56,17 +89,18 @@ func GetShellComment(shell string) string {
 		return "#!/bin/zsh"
 	case "bash":
 		return "#!/bin/bash"
+	case "fish":
+		return "#!/usr/bin/env fish"
+	case "nu":
+		return "#!/usr/bin/env nu"
+	case "pwsh":
+		return "#!/usr/bin/env pwsh"
 	default:
 		return "#!/bin/sh"
 	}
 }
 
 func HasDotwaifuIntegration(shell string) bool {
-	rcPath := GetRCFilePath(shell)
-	content, err := os.ReadFile(rcPath)
-	if err != nil {
-		return false
-	}
-
-	return strings.Contains(string(content), "dotwaifu Configuration")
+	integration, err := CheckIntegration(shell)
+	return err == nil && integration.Status != IntegrationMissing
 }
\ No newline at end of file
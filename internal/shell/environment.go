@@ -0,0 +1,111 @@
+package shell
+
+import (
+	"dotwaifu/internal/config"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// EnvironmentTypes are the module types that make up a project's
+// environment, as opposed to aliases and functions that only matter in an
+// interactive shell.
+var EnvironmentTypes = []string{"env", "paths"}
+
+// EnvironmentModules returns the env and paths modules of the given
+// projects, preceded by the core ones unless isolated is set, in load order
+// (see SortModules). Modules with unresolved sync conflicts are left out.
+func EnvironmentModules(projects []string, isolated bool) ([]Module, error) {
+	types, err := config.LoadTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	var modules []Module
+	scopes := projects
+	if !isolated {
+		scopes = append([]string{""}, projects...)
+	}
+
+	for _, project := range scopes {
+		dir := GetCoreDir()
+		if project != "" {
+			dir = filepath.Join(GetProjectsDir(), project)
+			if _, err := os.Stat(dir); err != nil {
+				return nil, fmt.Errorf("project '%s' does not exist", project)
+			}
+		}
+
+		scopeModules, err := listModulesIn(dir, project, types)
+		if err != nil {
+			return nil, err
+		}
+		for _, module := range scopeModules {
+			if contains(EnvironmentTypes, module.Type) && !module.HasConflict() {
+				modules = append(modules, module)
+			}
+		}
+	}
+
+	return SortModules(modules)
+}
+
+// CaptureEnvironment sources modules in a non-interactive subshell and
+// returns the environment it ends up with, in os.Environ form. Anything the
+// modules print goes to stderr.
+func CaptureEnvironment(shell string, modules []Module) ([]string, error) {
+	var script strings.Builder
+	script.WriteString(cacheHelper)
+	script.WriteString("{\n")
+	for _, module := range modules {
+		fmt.Fprintf(&script, "    source %s\n", shellQuote(module.Path))
+	}
+	script.WriteString("} >&2\nexec awk " + shellQuote(printEnvironment) + "\n")
+
+	cmd := exec.Command(ModuleShell(shell), "-c", script.String())
+	cmd.Env = append(os.Environ(), "DOTWAIFU_CONFIG_ROOT="+config.GetConfigDir())
+	cmd.Stderr = os.Stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("sourcing modules with %s: %w", ModuleShell(shell), err)
+	}
+
+	var env []string
+	for _, entry := range strings.Split(string(output), "\n") {
+		// "_" is the subshell's last command, not something the modules set
+		if entry == "" || strings.HasPrefix(entry, "_=") {
+			continue
+		}
+		env = append(env, environmentUnescaper.Replace(entry))
+	}
+	return env, nil
+}
+
+// printEnvironment prints one variable per line, with backslashes and
+// newlines in the values escaped, with any POSIX awk; env -0 is a GNU
+// extension.
+const printEnvironment = `
+function replace(s, from, to,    parts, n, i, out) {
+    n = split(s, parts, from)
+    out = parts[1]
+    for (i = 2; i <= n; i++) out = out to parts[i]
+    return out
+}
+BEGIN {
+    for (name in ENVIRON)
+        print name "=" replace(replace(ENVIRON[name], "\\", "\\\\"), "\n", "\\n")
+}`
+
+var environmentUnescaper = strings.NewReplacer(`\\`, `\`, `\n`, "\n")
+
+func contains(slice []string, item string) bool {
+	for _, s := range slice {
+		if s == item {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+package shell
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvironmentModules(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh":               "",
+		"core/aliases.sh":           "",
+		"core/paths.sh":             "",
+		"projects/flutter/env.sh":   "",
+		"projects/flutter/paths.sh": "",
+		"projects/flutter/misc.sh":  "",
+		"projects/node/env.sh":      "",
+	})
+
+	tests := []struct {
+		projects []string
+		isolated bool
+		want     []string
+	}{
+		{nil, false, []string{"core/env.sh", "core/paths.sh"}},
+		{[]string{"flutter"}, false, []string{"core/env.sh", "core/paths.sh", "projects/flutter/env.sh", "projects/flutter/paths.sh"}},
+		{[]string{"node", "flutter"}, true, []string{"projects/node/env.sh", "projects/flutter/env.sh", "projects/flutter/paths.sh"}},
+	}
+
+	for _, tt := range tests {
+		modules, err := EnvironmentModules(tt.projects, tt.isolated)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, module := range modules {
+			paths = append(paths, module.RelPath())
+		}
+		if !reflect.DeepEqual(paths, tt.want) {
+			t.Errorf("EnvironmentModules(%v, %v) = %v, want %v", tt.projects, tt.isolated, paths, tt.want)
+		}
+	}
+
+	if _, err := EnvironmentModules([]string{"rust"}, false); err == nil {
+		t.Error("EnvironmentModules accepted a project that does not exist")
+	}
+}
+
+func TestCaptureEnvironment(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	setupModules(t, map[string]string{
+		"core/env.sh":               "echo 'loading env'\nexport GREETING='hello\nworld'\nexport WINDIR='C:\\new'\nLOCAL_ONLY=1\n",
+		"projects/flutter/paths.sh": "export PATH=\"/opt/flutter/bin:$PATH\"\n",
+	})
+	t.Setenv("PATH", "/usr/bin:/bin")
+
+	modules, err := EnvironmentModules([]string{"flutter"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := CaptureEnvironment("bash", modules)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vars := make(map[string]string)
+	for _, entry := range env {
+		name, value, _ := strings.Cut(entry, "=")
+		vars[name] = value
+	}
+	if vars["GREETING"] != "hello\nworld" {
+		t.Errorf("GREETING = %q", vars["GREETING"])
+	}
+	if vars["WINDIR"] != `C:\new` {
+		t.Errorf("WINDIR = %q", vars["WINDIR"])
+	}
+	if vars["PATH"] != "/opt/flutter/bin:/usr/bin:/bin" {
+		t.Errorf("PATH = %q", vars["PATH"])
+	}
+	for _, name := range []string{"LOCAL_ONLY", "_"} {
+		if value, ok := vars[name]; ok {
+			t.Errorf("%s = %q is in the environment", name, value)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package shell
+
+import (
+	"dotwaifu/internal/config"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var bracedVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// GetFishModulesDir is where the fish translations of the modules are written.
+// The fish loader sources these instead of the .sh files.
+func GetFishModulesDir() string {
+	return filepath.Join(config.GetStateDir(), "fish")
+}
+
+// fishQuote renders a POSIX shell value as a fish string. It fails for
+// expansions fish cannot express the same way.
+func fishQuote(value string, quote byte) (string, bool) {
+	if quote == '\'' {
+		escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+		return "'" + escaped + "'", true
+	}
+
+	if strings.Contains(value, "$(") || strings.Contains(value, "`") {
+		return "", false
+	}
+
+	converted := bracedVarPattern.ReplaceAllString(value, "{$$$1}")
+	if strings.Contains(converted, "${") {
+		return "", false
+	}
+
+	prefix := ""
+	if quote == 0 && (converted == "~" || strings.HasPrefix(converted, "~/")) {
+		prefix, converted = "~", strings.TrimPrefix(converted, "~")
+	}
+	if converted == "" {
+		return prefix, true
+	}
+	return prefix + `"` + converted + `"`, true
+}
+
+// TranslateToFish rewrites the statically understood lines of a module in
+// fish syntax. Lines it cannot translate are kept as comments and returned so
+// they can be reported.
+func TranslateToFish(content string) (string, []Statement) {
+	var out strings.Builder
+	var untranslated []Statement
+
+	for _, statement := range ParseModule(content) {
+		line, ok := translateFishStatement(statement)
+		if !ok {
+			untranslated = append(untranslated, statement)
+			line = "# dotwaifu: untranslated: " + statement.Raw
+		} else if statement.Comment != "" && statement.Kind != StatementComment {
+			line += "  # " + statement.Comment
+		}
+		out.WriteString(line + "\n")
+	}
+
+	return out.String(), untranslated
+}
+
+func translateFishStatement(statement Statement) (string, bool) {
+	switch statement.Kind {
+	case StatementBlank, StatementComment:
+		return statement.Raw, true
+	case StatementExport:
+		value, ok := fishQuote(statement.Value, statement.Quote)
+		if !ok {
+			return "", false
+		}
+		scope := "-g"
+		if statement.Exported {
+			scope = "-gx"
+		}
+		return fmt.Sprintf("set %s %s %s", scope, statement.Name, value), true
+	case StatementPath:
+		var entries []string
+		for _, entry := range statement.Entries {
+			value, ok := fishQuote(entry, statement.Quote)
+			if !ok {
+				return "", false
+			}
+			entries = append(entries, value)
+		}
+		if statement.Append {
+			return "set -gx PATH $PATH " + strings.Join(entries, " "), true
+		}
+		return "set -gx PATH " + strings.Join(entries, " ") + " $PATH", true
+	case StatementAlias:
+		value, ok := fishQuote(statement.Value, statement.Quote)
+		if !ok {
+			return "", false
+		}
+		return fmt.Sprintf("alias %s %s", statement.Name, value), true
+	}
+
+	return "", false
+}
+
+// GenerateFishModules writes a fish translation of every module and returns
+// the lines that could not be translated, keyed by module path relative to
+// shell/shared.
+func GenerateFishModules() (map[string][]Statement, error) {
+	modules, err := LoadOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	fishDir := GetFishModulesDir()
+	if err := os.RemoveAll(fishDir); err != nil {
+		return nil, err
+	}
+
+	untranslated := make(map[string][]Statement)
+	var loaded []string
+	for _, module := range modules {
+		content, err := os.ReadFile(module.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		translated, skipped := TranslateToFish(string(content))
+		if len(skipped) > 0 {
+			untranslated[module.RelPath()] = skipped
+		}
+
+		target := filepath.Join(fishDir, filepath.FromSlash(strings.TrimSuffix(module.RelPath(), ".sh")+".fish"))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+
+		header := fmt.Sprintf("# Translated from %s by dotwaifu - DO NOT EDIT MANUALLY\n", module.RelPath())
+		if err := os.WriteFile(target, []byte(header+translated), 0644); err != nil {
+			return nil, err
+		}
+
+		rel, _ := fishQuote(strings.TrimSuffix(module.RelPath(), ".sh"), '\'')
+		loaded = append(loaded, rel)
+	}
+
+	if err := os.MkdirAll(fishDir, 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(filepath.Join(fishDir, "loader.fish"), []byte(fishLoaderScript(loaded)), 0644); err != nil {
+		return nil, err
+	}
+
+	return untranslated, nil
+}
+
+// fishLoaderScript sources the translated modules, given as quoted paths
+// relative to the fish modules directory without extension, in load order.
+func fishLoaderScript(modules []string) string {
+	var out strings.Builder
+	out.WriteString("# Generated by dotwaifu from ~/.config/dotwaifu/shell/shared - DO NOT EDIT MANUALLY\n")
+	out.WriteString("# 'dotwaifu reload' regenerates it\n\n")
+	out.WriteString("set -l dotwaifu_root \"$HOME/.config/dotwaifu\"\n")
+	if len(modules) == 0 {
+		return out.String()
+	}
+
+	out.WriteString("# DOTWAIFU_SAFE=1 starts the shell without any modules, and\n")
+	out.WriteString("# DOTWAIFU_SKIP=core/scripts,projects/flutter without the listed ones\n")
+	out.WriteString("if set -q DOTWAIFU_SAFE; and test \"$DOTWAIFU_SAFE\" != 0\n")
+	out.WriteString("    echo \"dotwaifu: safe mode, no modules loaded\" >&2\n")
+	out.WriteString("    return\nend\n")
+	out.WriteString("set -l dotwaifu_skip (string split , -- \"$DOTWAIFU_SKIP\" | string replace -r '\\.sh$' '')\n\n")
+	fmt.Fprintf(&out, "for rel in %s\n", strings.Join(modules, " "))
+	out.WriteString("    test -e \"$dotwaifu_root/state/conflicts/shell/shared/$rel.sh\"; and continue\n")
+	out.WriteString("    contains -- $rel $dotwaifu_skip; or contains -- (string replace -r '/[^/]*$' '' -- $rel) $dotwaifu_skip; and continue\n")
+	out.WriteString("    set -l config \"$dotwaifu_root/state/fish/$rel.fish\"\n")
+	out.WriteString("    test -r $config; and source $config\n")
+	out.WriteString("end\n")
+	return out.String()
+}
+
+func fishLoadingLogic() string {
+	return `set -l dotwaifu_root "$HOME/.config/dotwaifu"
+set -l dotwaifu_fish "$dotwaifu_root/state/fish"
+
+# Fish cannot read the .sh modules directly; 'dotwaifu reload' translates
+# them into $dotwaifu_fish, along with loader.fish which sources them in load
+# order. Files with unresolved sync conflicts are skipped.
+if test -r $dotwaifu_fish/loader.fish
+    source $dotwaifu_fish/loader.fish
+else
+    for config in $dotwaifu_fish/core/*.fish $dotwaifu_fish/projects/*/*.fish
+        set -l rel (string replace -r '^.*/state/fish/(.*)\.fish$' '$1' -- $config)
+        test -e "$dotwaifu_root/state/conflicts/shell/shared/$rel.sh"; and continue
+        test -r $config; and source $config
+    end
+end`
+}
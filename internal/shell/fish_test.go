@@ -0,0 +1,118 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTranslateToFish(t *testing.T) {
+	tests := []struct {
+		name         string
+		in           string
+		want         string
+		untranslated int
+	}{
+		{
+			name: "comments and blank lines",
+			in:   "# env\n\n",
+			want: "# env\n\n",
+		},
+		{
+			name: "exported and plain variables",
+			in:   "export EDITOR=\"nvim\"\nHISTSIZE=1000\n",
+			want: "set -gx EDITOR \"nvim\"\nset -g HISTSIZE \"1000\"\n",
+		},
+		{
+			name: "braced variables",
+			in:   "export NVIM_DIR=\"${XDG_CONFIG_HOME}/nvim\"\n",
+			want: "set -gx NVIM_DIR \"{$XDG_CONFIG_HOME}/nvim\"\n",
+		},
+		{
+			name:         "single quotes are escaped",
+			in:           "alias say='echo it'\\''s'\nexport RAW='a\\b $HOME'\n",
+			want:         "# dotwaifu: untranslated: alias say='echo it'\\''s'\nset -gx RAW 'a\\\\b $HOME'\n",
+			untranslated: 1,
+		},
+		{
+			name: "bare tilde",
+			in:   "export NOTES=~/notes\nexport TILDE=~\n",
+			want: "set -gx NOTES ~\"/notes\"\nset -gx TILDE ~\n",
+		},
+		{
+			name: "prepended and appended PATH",
+			in:   "export PATH=\"$HOME/bin:/opt/bin:$PATH\"\nexport PATH=$PATH:~/go/bin\n",
+			want: "set -gx PATH \"$HOME/bin\" \"/opt/bin\" $PATH\nset -gx PATH $PATH ~\"/go/bin\"\n",
+		},
+		{
+			name: "aliases keep trailing comments",
+			in:   "alias ll='ls -la' # long\n",
+			want: "alias ll 'ls -la'  # long\n",
+		},
+		{
+			name:         "command substitution",
+			in:           "export TODAY=\"$(date +%F)\"\n",
+			want:         "# dotwaifu: untranslated: export TODAY=\"$(date +%F)\"\n",
+			untranslated: 1,
+		},
+		{
+			name:         "parameter expansion",
+			in:           "export EDITOR=\"${VISUAL:-vim}\"\n",
+			want:         "# dotwaifu: untranslated: export EDITOR=\"${VISUAL:-vim}\"\n",
+			untranslated: 1,
+		},
+		{
+			name:         "shell code",
+			in:           "if [ -d ~/bin ]; then PATH=~/bin:$PATH; fi\n",
+			want:         "# dotwaifu: untranslated: if [ -d ~/bin ]; then PATH=~/bin:$PATH; fi\n",
+			untranslated: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, untranslated := TranslateToFish(tt.in)
+			if got != tt.want {
+				t.Errorf("TranslateToFish =\n%s\nwant\n%s", got, tt.want)
+			}
+			if len(untranslated) != tt.untranslated {
+				t.Errorf("%d untranslated lines, want %d", len(untranslated), tt.untranslated)
+			}
+		})
+	}
+}
+
+func TestGenerateFishModules(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh":              "export EDITOR=\"nvim\"\n",
+		"projects/rust/scripts.sh": "cargo_clean() {\n    cargo clean\n}\n",
+	})
+
+	untranslated, err := GenerateFishModules()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(GetFishModulesDir(), "core", "env.fish"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# Translated from core/env.sh by dotwaifu - DO NOT EDIT MANUALLY\nset -gx EDITOR \"nvim\"\n"
+	if string(content) != want {
+		t.Errorf("env.fish = %q, want %q", content, want)
+	}
+
+	if len(untranslated) != 1 || len(untranslated["projects/rust/scripts.sh"]) == 0 {
+		t.Errorf("untranslated = %v", untranslated)
+	}
+}
+
+func TestDetectFish(t *testing.T) {
+	t.Setenv("SHELL", "/usr/bin/fish")
+	if got := DetectShell(); got != "fish" {
+		t.Errorf("DetectShell = %q, want fish", got)
+	}
+	if got := GetRCFileName("fish"); got != "dotwaifu.fish" {
+		t.Errorf("GetRCFileName(fish) = %q", got)
+	}
+}
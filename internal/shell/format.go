@@ -0,0 +1,265 @@
+package shell
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// NotSimpleError is returned by FormatModule for modules with lines it does
+// not understand, which it leaves alone.
+type NotSimpleError struct {
+	Line int
+}
+
+func (e *NotSimpleError) Error() string {
+	return fmt.Sprintf("line %d is not a simple alias, export or PATH statement", e.Line)
+}
+
+// formatLine is a line of a module in canonical form.
+type formatLine struct {
+	Statement Statement
+	Text      string
+}
+
+// FormatModule rewrites a module made only of aliases, variable assignments,
+// PATH changes, comments and blank lines in canonical form:
+//
+//   - aliases are single-quoted and variables double-quoted, unless that
+//     would change what the value means
+//   - PATH changes read export PATH="dir:$PATH" or export PATH="$PATH:dir"
+//   - exact duplicates of an earlier line are dropped, unless a line in
+//     between sets the same alias or variable, or one the duplicate uses
+//   - runs of blank lines become one, and the file ends with one newline
+//
+// With sortSections, the statements between comments and blank lines are
+// sorted by name if they are all aliases, or all variables that do not refer
+// to each other. PATH changes keep their order.
+func FormatModule(content string, sortSections bool) (string, error) {
+	var lines []formatLine
+	for _, statement := range ParseModule(content) {
+		text, ok := canonicalStatement(statement)
+		if !ok {
+			return "", &NotSimpleError{Line: statement.Line}
+		}
+		lines = append(lines, formatLine{Statement: statement, Text: text})
+	}
+
+	lines = dropDuplicates(lines)
+	if sortSections {
+		sortStatements(lines)
+	}
+
+	var out strings.Builder
+	blank := false
+	for _, line := range lines {
+		if line.Statement.Kind == StatementBlank {
+			blank = out.Len() > 0
+			continue
+		}
+		if blank {
+			out.WriteString("\n")
+			blank = false
+		}
+		out.WriteString(line.Text + "\n")
+	}
+	return out.String(), nil
+}
+
+// canonicalStatement renders a single statement in canonical form, or
+// reports that it is not one of the simple kinds.
+func canonicalStatement(statement Statement) (string, bool) {
+	var text string
+	switch statement.Kind {
+	case StatementBlank:
+		return "", true
+	case StatementComment:
+		return strings.TrimSpace(statement.Raw), true
+	case StatementAlias:
+		text = "alias " + statement.Name + "=" + canonicalValue(statement, '\'')
+	case StatementExport:
+		text = statement.Name + "=" + canonicalValue(statement, '"')
+		if statement.Exported {
+			text = "export " + text
+		}
+	case StatementPath:
+		if statement.Quote == 0 && strings.Contains(statement.Value, "~") {
+			// Only a bare ~ expands, quoting it would break the entry
+			text = "export PATH=" + statement.Value
+		} else if statement.Append {
+			text = `export PATH="$PATH:` + strings.Join(statement.Entries, ":") + `"`
+		} else {
+			text = `export PATH="` + strings.Join(statement.Entries, ":") + `:$PATH"`
+		}
+	default:
+		return "", false
+	}
+
+	if statement.Comment != "" {
+		text += " # " + statement.Comment
+	}
+	return text, true
+}
+
+// canonicalValue quotes the value of statement with preferred if it is a
+// literal string, and otherwise keeps it the way it was written. A bare
+// value with expansions becomes double-quoted where that means the same.
+func canonicalValue(statement Statement, preferred byte) string {
+	value := statement.Value
+	literal := false
+	switch statement.Quote {
+	case '\'':
+		literal = true
+	case '"':
+		literal = !strings.ContainsAny(value, "$`\\")
+	default:
+		// Bare alias values are globbed, and a bare ~ expands
+		literal = !strings.ContainsAny(value, "$`\\~*?[")
+		if !literal {
+			if statement.Kind == StatementAlias || strings.ContainsAny(value, "~\\") {
+				return value
+			}
+			return `"` + value + `"`
+		}
+	}
+
+	if !literal {
+		return `"` + value + `"`
+	}
+	return quoteLiteral(value, preferred)
+}
+
+// quoteLiteral quotes s so that the shell reads it back unchanged,
+// preferably with the given quote character.
+func quoteLiteral(s string, preferred byte) string {
+	if preferred == '"' && !strings.ContainsAny(s, "$`\"\\") {
+		return `"` + s + `"`
+	}
+	if !strings.Contains(s, "'") {
+		return "'" + s + "'"
+	}
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`")
+	return `"` + replacer.Replace(s) + `"`
+}
+
+// assignedName returns what a statement sets, if anything: "alias NAME" for
+// aliases and the variable name otherwise.
+func assignedName(statement Statement) string {
+	switch statement.Kind {
+	case StatementAlias:
+		return "alias " + statement.Name
+	case StatementExport:
+		return statement.Name
+	case StatementPath:
+		return "PATH"
+	}
+	return ""
+}
+
+// referencedVariables returns the variables a statement's value uses.
+// Values that cannot be analyzed refer to everything, reported as "*".
+func referencedVariables(statement Statement) []string {
+	if statement.Kind != StatementExport && statement.Kind != StatementPath && statement.Kind != StatementAlias {
+		return nil
+	}
+	parts, ok := ValueParts(statement.Value, statement.Quote)
+	if !ok {
+		return []string{"*"}
+	}
+
+	var names []string
+	for _, part := range parts {
+		if part.Variable != "" {
+			names = append(names, part.Variable)
+		}
+	}
+	return names
+}
+
+// dropDuplicates removes statements whose canonical text already appeared,
+// as long as no statement in between set a variable they use.
+func dropDuplicates(lines []formatLine) []formatLine {
+	seen := make(map[string]int)
+	var kept []formatLine
+	for _, line := range lines {
+		kind := line.Statement.Kind
+		if kind == StatementBlank || kind == StatementComment {
+			kept = append(kept, line)
+			continue
+		}
+
+		if first, ok := seen[line.Text]; ok && !changedBetween(kept[first+1:], line.Statement) {
+			continue
+		}
+		seen[line.Text] = len(kept)
+		kept = append(kept, line)
+	}
+	return kept
+}
+
+// changedBetween reports whether one of lines sets what statement sets or a
+// variable it uses, so that dropping statement would change the result.
+func changedBetween(lines []formatLine, statement Statement) bool {
+	target := assignedName(statement)
+	used := referencedVariables(statement)
+	for _, line := range lines {
+		assigned := assignedName(line.Statement)
+		if assigned == "" {
+			continue
+		}
+		if assigned == target {
+			return true
+		}
+		for _, variable := range used {
+			if variable == assigned || variable == "*" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sortStatements sorts each run of statements between comments and blank
+// lines by name where that cannot change what the module does.
+func sortStatements(lines []formatLine) {
+	start := 0
+	for i := 0; i <= len(lines); i++ {
+		if i < len(lines) && lines[i].Statement.Kind != StatementBlank && lines[i].Statement.Kind != StatementComment {
+			continue
+		}
+		if section := lines[start:i]; sortable(section) {
+			sort.SliceStable(section, func(a, b int) bool { return section[a].Statement.Name < section[b].Statement.Name })
+		}
+		start = i + 1
+	}
+}
+
+func sortable(section []formatLine) bool {
+	if len(section) < 2 {
+		return false
+	}
+
+	kind := section[0].Statement.Kind
+	assigned := make(map[string]bool)
+	for _, line := range section {
+		if line.Statement.Kind != kind {
+			return false
+		}
+		assigned[line.Statement.Name] = true
+	}
+
+	switch kind {
+	case StatementAlias:
+		return true
+	case StatementExport:
+		for _, line := range section {
+			for _, variable := range referencedVariables(line.Statement) {
+				if variable == "*" || assigned[variable] {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
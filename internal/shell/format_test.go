@@ -0,0 +1,123 @@
+package shell
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFormatModule(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		sort bool
+		want string
+	}{
+		{
+			name: "canonical quoting",
+			in:   "alias ll=\"ls -la\"\nexport EDITOR=nvim\nexport PATH=$HOME/bin:$PATH\n",
+			want: "alias ll='ls -la'\nexport EDITOR=\"nvim\"\nexport PATH=\"$HOME/bin:$PATH\"\n",
+		},
+		{
+			name: "expansions keep double quotes",
+			in:   "alias gs=\"git status $FLAGS\"\nexport GOPATH=\"$HOME/go\"\n",
+			want: "alias gs=\"git status $FLAGS\"\nexport GOPATH=\"$HOME/go\"\n",
+		},
+		{
+			name: "bare tilde stays bare",
+			in:   "export PATH=~/bin:$PATH\n",
+			want: "export PATH=~/bin:$PATH\n",
+		},
+		{
+			name: "appended PATH",
+			in:   "export PATH=\"$PATH:/opt/bin\"\n",
+			want: "export PATH=\"$PATH:/opt/bin\"\n",
+		},
+		{
+			name: "trailing comment",
+			in:   "alias g=git   #short\n",
+			want: "alias g='git' # short\n",
+		},
+		{
+			name: "blank lines collapse",
+			in:   "\n\n# aliases\n\n\n\nalias g=git\n\n\n",
+			want: "# aliases\n\nalias g='git'\n",
+		},
+		{
+			name: "exact duplicates are dropped",
+			in:   "alias g=git\nalias h=hg\nalias g='git'\n",
+			want: "alias g='git'\nalias h='hg'\n",
+		},
+		{
+			name: "alias redefined in between",
+			in:   "alias ll='ls -l'\nalias ll='ls -la'\nalias ll='ls -l'\n",
+			want: "alias ll='ls -l'\nalias ll='ls -la'\nalias ll='ls -l'\n",
+		},
+		{
+			name: "variable reassigned in between",
+			in:   "export EDITOR=\"vim\"\nexport EDITOR=\"nano\"\nexport EDITOR=\"vim\"\n",
+			want: "export EDITOR=\"vim\"\nexport EDITOR=\"nano\"\nexport EDITOR=\"vim\"\n",
+		},
+		{
+			name: "used variable changed in between",
+			in:   "export A=\"$B/x\"\nexport B=\"/tmp\"\nexport A=\"$B/x\"\n",
+			want: "export A=\"$B/x\"\nexport B=\"/tmp\"\nexport A=\"$B/x\"\n",
+		},
+		{
+			name: "PATH order changed in between",
+			in:   "export PATH=\"/a:$PATH\"\nexport PATH=\"/b:$PATH\"\nexport PATH=\"/a:$PATH\"\n",
+			want: "export PATH=\"/a:$PATH\"\nexport PATH=\"/b:$PATH\"\nexport PATH=\"/a:$PATH\"\n",
+		},
+		{
+			name: "repeated PATH change is dropped",
+			in:   "export PATH=\"/a:$PATH\"\nexport GOPATH=\"/go\"\nexport PATH=\"/a:$PATH\"\n",
+			want: "export PATH=\"/a:$PATH\"\nexport GOPATH=\"/go\"\n",
+		},
+		{
+			name: "sort aliases within blocks",
+			in:   "# git\nalias gs='git status'\nalias ga='git add'\n\nalias b='x'\nalias a='y'\n",
+			sort: true,
+			want: "# git\nalias ga='git add'\nalias gs='git status'\n\nalias a='y'\nalias b='x'\n",
+		},
+		{
+			name: "sort leaves dependent variables alone",
+			in:   "export B=\"$A/b\"\nexport A=\"/a\"\n",
+			sort: true,
+			want: "export B=\"$A/b\"\nexport A=\"/a\"\n",
+		},
+		{
+			name: "sort leaves PATH alone",
+			in:   "export PATH=\"/b:$PATH\"\nexport PATH=\"/a:$PATH\"\n",
+			sort: true,
+			want: "export PATH=\"/b:$PATH\"\nexport PATH=\"/a:$PATH\"\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FormatModule(tt.in, tt.sort)
+			if err != nil {
+				t.Fatalf("FormatModule: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("FormatModule =\n%s\nwant\n%s", got, tt.want)
+			}
+
+			again, err := FormatModule(got, tt.sort)
+			if err != nil || again != got {
+				t.Errorf("formatting again changed the result:\n%s", again)
+			}
+		})
+	}
+}
+
+func TestFormatModuleNotSimple(t *testing.T) {
+	_, err := FormatModule("alias g=git\nif true; then echo hi; fi\n", false)
+
+	var notSimple *NotSimpleError
+	if !errors.As(err, &notSimple) {
+		t.Fatalf("err = %v, want NotSimpleError", err)
+	}
+	if notSimple.Line != 2 {
+		t.Errorf("Line = %d, want 2", notSimple.Line)
+	}
+}
@@ -1,43 +1,99 @@
 package shell
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
 )
 
-func GenerateRCContent(shell string, isExisting bool) string {
-	comment := GetShellComment(shell)
+const (
+	integrationStartMarker = "# === dotwaifu Configuration (Added by dotwaifu) ==="
+	integrationEndMarker   = "# === End dotwaifu Configuration ==="
+	generatedMarker        = "# Generated by dotwaifu - DO NOT EDIT MANUALLY"
+)
 
-	configRoot := "$HOME/.config/dotwaifu"
+// LoaderVersion is stamped into every loader written to an RC file. Bump it
+// whenever the loading logic changes so 'dotwaifu repair' upgrades existing
+// installs.
+const LoaderVersion = 5
 
-	loadingLogic := fmt.Sprintf(`DOTWAIFU_CONFIG_ROOT="%s"
+var loaderStampPattern = regexp.MustCompile(`(?m)^# dotwaifu loader v(\d+) sha256:([0-9a-f]+)$`)
 
-# Load core configurations
-for config in "$DOTWAIFU_CONFIG_ROOT"/shell/shared/core/*.sh; do
-    [ -r "$config" ] && source "$config"
-done
+// loaderChecksum identifies the exact loading logic a stamp was written for,
+// so hand edits to the block can be told apart from older versions.
+func loaderChecksum(loadingLogic string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(loadingLogic)))
+	return hex.EncodeToString(sum[:])[:16]
+}
 
-# Load project-specific configurations
-for project_dir in "$DOTWAIFU_CONFIG_ROOT"/shell/shared/projects/*/; do
-    [ -d "$project_dir" ] || continue
-    for config in "$project_dir"*.sh; do
-        [ -r "$config" ] && source "$config"
-    done
-done`, configRoot)
+func loaderStamp(loadingLogic string) string {
+	return fmt.Sprintf("# dotwaifu loader v%d sha256:%s", LoaderVersion, loaderChecksum(loadingLogic))
+}
+
+func GenerateRCContent(shell string, isExisting bool) (string, error) {
+	var loadingLogic string
+	switch shell {
+	case "fish":
+		loadingLogic = fishLoadingLogic()
+	case "nu":
+		loadingLogic = nushellLoadingLogic()
+	case "pwsh":
+		var err error
+		if loadingLogic, err = powerShellLoadingLogic(); err != nil {
+			return "", err
+		}
+	default:
+		loadingLogic = posixLoadingLogic()
+	}
 
 	if isExisting {
 		return fmt.Sprintf(`
-# === dotwaifu Configuration (Added by dotwaifu) ===
 %s
-# === End dotwaifu Configuration ===`, loadingLogic)
+%s
+%s
+%s`, integrationStartMarker, loaderStamp(loadingLogic), loadingLogic, integrationEndMarker), nil
 	}
 
 	return fmt.Sprintf(`%s
-# Generated by dotwaifu - DO NOT EDIT MANUALLY
+%s
 # Edit files in ~/.config/dotwaifu/shell/shared/ instead
+%s
 
-%s`, comment, loadingLogic)
+%s`, GetShellComment(shell), generatedMarker, loaderStamp(loadingLogic), loadingLogic), nil
+}
+
+func posixLoadingLogic() string {
+	// The bundle is written by 'dotwaifu build' and the compiled loader by
+	// GenerateLoader. Until one of them exists every module is sourced; files
+	// with unresolved sync conflicts are skipped until they are resolved, see
+	// git.ConflictMarkerPath. DOTWAIFU_SAFE and DOTWAIFU_SKIP work as in the
+	// loader, see safeModeCheck. This is not a format string, so that the
+	// parameter expansions read as the shell sees them
+	return `DOTWAIFU_CONFIG_ROOT="$HOME/.config/dotwaifu"
+
+if [ -r "$DOTWAIFU_CONFIG_ROOT/state/bundle.sh" ]; then
+    source "$DOTWAIFU_CONFIG_ROOT/state/bundle.sh"
+elif [ -r "$DOTWAIFU_CONFIG_ROOT/state/loader.sh" ]; then
+    source "$DOTWAIFU_CONFIG_ROOT/state/loader.sh"
+elif [ "${DOTWAIFU_SAFE:-0}" != 0 ]; then
+    echo "dotwaifu: safe mode, no modules loaded" >&2
+else
+    # Load core, then project-specific configurations
+    for config in "$DOTWAIFU_CONFIG_ROOT"/shell/shared/core/*.sh "$DOTWAIFU_CONFIG_ROOT"/shell/shared/projects/*/*.sh; do
+        config_rel=${config#"$DOTWAIFU_CONFIG_ROOT"/shell/shared/}
+        [ -e "$DOTWAIFU_CONFIG_ROOT/state/conflicts/shell/shared/$config_rel" ] && continue
+        case ",$DOTWAIFU_SKIP," in
+            *",$config_rel,"*|*",${config_rel%.sh},"*|*",${config_rel%/*},"*) continue ;;
+        esac
+        [ -r "$config" ] && source "$config"
+    done
+    unset config_rel
+fi`
 }
 
 func BackupExistingRC(shell string) error {
@@ -54,7 +110,10 @@ func BackupExistingRC(shell string) error {
 
 func AppendToExistingRC(shell string) error {
 	rcPath := GetRCFilePath(shell)
-	content := GenerateRCContent(shell, true)
+	content, err := GenerateRCContent(shell, true)
+	if err != nil {
+		return err
+	}
 
 	file, err := os.OpenFile(rcPath, os.O_APPEND|os.O_WRONLY, 0644)
 	if err != nil {
@@ -68,7 +127,14 @@ func AppendToExistingRC(shell string) error {
 
 func CreateNewRC(shell string) error {
 	rcPath := GetRCFilePath(shell)
-	content := GenerateRCContent(shell, false)
+	content, err := GenerateRCContent(shell, false)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(rcPath), 0755); err != nil {
+		return err
+	}
 
 	return os.WriteFile(rcPath, []byte(content), 0644)
 }
@@ -92,14 +158,11 @@ func RemoveIntegration(shell string) error {
 	contentStr := string(content)
 
 	// Remove the dotwaifu integration block
-	startMarker := "# === dotwaifu Configuration (Added by dotwaifu) ==="
-	endMarker := "# === End dotwaifu Configuration ==="
-
-	startIdx := strings.Index(contentStr, startMarker)
+	startIdx := strings.Index(contentStr, integrationStartMarker)
 	if startIdx == -1 {
 		// No integration block found, file might be generated by dotwaifu
 		// Check if it's completely generated (starts with shell comment and "Generated by dotwaifu")
-		if strings.Contains(contentStr, "# Generated by dotwaifu - DO NOT EDIT MANUALLY") {
+		if strings.Contains(contentStr, generatedMarker) {
 			// This is a dotwaifu-generated file, safe to remove
 			return os.Remove(rcPath)
 		}
@@ -107,7 +170,7 @@ func RemoveIntegration(shell string) error {
 		return nil
 	}
 
-	endIdx := strings.Index(contentStr[startIdx:], endMarker)
+	endIdx := strings.Index(contentStr[startIdx:], integrationEndMarker)
 	if endIdx == -1 {
 		// Malformed integration, can't safely remove
 		return fmt.Errorf("malformed dotwaifu integration found in %s", rcPath)
@@ -115,7 +178,7 @@ func RemoveIntegration(shell string) error {
 
 	// Remove the integration block
 	before := contentStr[:startIdx]
-	after := contentStr[startIdx+endIdx+len(endMarker):]
+	after := contentStr[startIdx+endIdx+len(integrationEndMarker):]
 
 	// Clean up extra newlines
 	cleanedContent := strings.TrimSpace(before + after)
@@ -124,4 +187,198 @@ func RemoveIntegration(shell string) error {
 	}
 
 	return os.WriteFile(rcPath, []byte(cleanedContent), 0644)
-}
\ No newline at end of file
+}
+
+// TranslateModule converts module content into the syntax of shell. POSIX
+// shells get the content unchanged.
+func TranslateModule(shell, content string) (string, []Statement) {
+	switch shell {
+	case "fish":
+		return TranslateToFish(content)
+	case "nu":
+		return translateNushellModule(content)
+	case "pwsh":
+		return TranslateToPowerShell(content)
+	default:
+		return content, nil
+	}
+}
+
+// GenerateTranslations refreshes what shell loads at startup: the compiled
+// loader for POSIX shells, or the translated copies of the modules for the
+// others. It returns the lines that could not be translated, keyed by module
+// path relative to shell/shared.
+func GenerateTranslations(shell string) (map[string][]Statement, error) {
+	switch shell {
+	case "fish":
+		return GenerateFishModules()
+	case "nu":
+		return GenerateNushellModules()
+	case "pwsh":
+		return RegeneratePowerShellProfile()
+	default:
+		if err := GenerateLoader(); err != nil {
+			return nil, err
+		}
+		if !BundleExists() {
+			return nil, nil
+		}
+		if err := BuildBundle(shell); err != nil {
+			return nil, err
+		}
+		if shell == "zsh" {
+			// Without zcompile zsh simply sources the bundle
+			CompileBundle()
+		}
+		return nil, nil
+	}
+}
+
+// replaceIntegrationBlock swaps the marked dotwaifu block in content for
+// block, which must carry its own markers.
+func replaceIntegrationBlock(content, block string) (string, error) {
+	startIdx := strings.Index(content, integrationStartMarker)
+	if startIdx == -1 {
+		return "", fmt.Errorf("no dotwaifu integration block found")
+	}
+
+	endIdx := strings.Index(content[startIdx:], integrationEndMarker)
+	if endIdx == -1 {
+		return "", fmt.Errorf("malformed dotwaifu integration block")
+	}
+
+	before := content[:startIdx]
+	after := content[startIdx+endIdx+len(integrationEndMarker):]
+	return before + strings.TrimPrefix(block, "\n") + after, nil
+}
+
+// IntegrationStatus describes the state of the dotwaifu block in an RC file.
+type IntegrationStatus int
+
+const (
+	IntegrationMissing IntegrationStatus = iota
+	IntegrationMalformed
+	IntegrationOutdated
+	IntegrationModified
+	IntegrationCurrent
+)
+
+func (s IntegrationStatus) String() string {
+	switch s {
+	case IntegrationMalformed:
+		return "malformed"
+	case IntegrationOutdated:
+		return "outdated"
+	case IntegrationModified:
+		return "modified"
+	case IntegrationCurrent:
+		return "current"
+	default:
+		return "missing"
+	}
+}
+
+// Integration is the result of inspecting the loader in an RC file. Version
+// is 0 for loaders written before they were stamped.
+type Integration struct {
+	Status  IntegrationStatus
+	Version int
+}
+
+// inspectLoader compares the loader text of an RC file (the block between the
+// markers, or a whole generated file) with the text dotwaifu would write now.
+func inspectLoader(text, expected string) Integration {
+	match := loaderStampPattern.FindStringSubmatchIndex(text)
+	if match == nil {
+		return Integration{Status: IntegrationOutdated}
+	}
+
+	version, _ := strconv.Atoi(text[match[2]:match[3]])
+	checksum := text[match[4]:match[5]]
+
+	if loaderChecksum(text[match[1]:]) != checksum {
+		return Integration{Status: IntegrationModified, Version: version}
+	}
+	if version < LoaderVersion || strings.TrimSpace(text) != strings.TrimSpace(expected) {
+		return Integration{Status: IntegrationOutdated, Version: version}
+	}
+	return Integration{Status: IntegrationCurrent, Version: version}
+}
+
+// findIntegrationBlock returns the bounds of the text between the markers.
+// The block is well-formed when it has exactly one start marker followed by
+// one end marker.
+func findIntegrationBlock(content string) (start, end int, ok bool) {
+	if strings.Count(content, integrationStartMarker) != 1 || strings.Count(content, integrationEndMarker) != 1 {
+		return 0, 0, false
+	}
+
+	start = strings.Index(content, integrationStartMarker) + len(integrationStartMarker)
+	end = strings.Index(content, integrationEndMarker)
+	return start, end, end >= start
+}
+
+// CheckIntegration inspects the RC file of shell: whether it loads dotwaifu,
+// whether the block is well-formed, and whether the loader is the current
+// version and unmodified.
+func CheckIntegration(shell string) (Integration, error) {
+	content, err := os.ReadFile(GetRCFilePath(shell))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return Integration{Status: IntegrationMissing}, nil
+		}
+		return Integration{}, err
+	}
+	contentStr := string(content)
+
+	if !strings.Contains(contentStr, integrationStartMarker) && !strings.Contains(contentStr, integrationEndMarker) {
+		if !strings.Contains(contentStr, generatedMarker) {
+			return Integration{Status: IntegrationMissing}, nil
+		}
+		expected, err := GenerateRCContent(shell, false)
+		if err != nil {
+			return Integration{}, err
+		}
+		return inspectLoader(contentStr, expected), nil
+	}
+
+	start, end, ok := findIntegrationBlock(contentStr)
+	if !ok {
+		return Integration{Status: IntegrationMalformed}, nil
+	}
+
+	block, err := GenerateRCContent(shell, true)
+	if err != nil {
+		return Integration{}, err
+	}
+	expected := strings.TrimPrefix(block, "\n"+integrationStartMarker)
+	expected = strings.TrimSuffix(expected, integrationEndMarker)
+	return inspectLoader(contentStr[start:end], expected), nil
+}
+
+// RepairedRC returns the current content of the RC file of shell and the
+// content with its loader rewritten to the current version. Only the text
+// between the markers changes; a generated RC file is regenerated whole.
+func RepairedRC(shell string) (current, repaired string, err error) {
+	content, err := os.ReadFile(GetRCFilePath(shell))
+	if err != nil {
+		return "", "", err
+	}
+	current = string(content)
+
+	if !strings.Contains(current, integrationStartMarker) && strings.Contains(current, generatedMarker) {
+		repaired, err = GenerateRCContent(shell, false)
+		return current, repaired, err
+	}
+
+	if _, _, ok := findIntegrationBlock(current); !ok {
+		return "", "", fmt.Errorf("the dotwaifu block in %s is malformed", GetRCFilePath(shell))
+	}
+
+	block, err := GenerateRCContent(shell, true)
+	if err != nil {
+		return "", "", err
+	}
+	repaired, err = replaceIntegrationBlock(current, block)
+	return current, repaired, err
+}
@@ -0,0 +1,167 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// writeRC replaces the bash RC file in the current HOME.
+func writeRC(t *testing.T, content string) {
+	t.Helper()
+	if err := os.WriteFile(GetRCFilePath("bash"), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// bashRC returns the dotwaifu block for an existing bash RC file, or a whole
+// generated one.
+func bashRC(t *testing.T, isExisting bool) string {
+	t.Helper()
+	content, err := GenerateRCContent("bash", isExisting)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestCheckIntegration(t *testing.T) {
+	setupModules(t, nil)
+	block := strings.TrimPrefix(bashRC(t, true), "\n")
+	modified := strings.Replace(block, integrationEndMarker, "export EDITED=1\n"+integrationEndMarker, 1)
+	unstamped := loaderStampPattern.ReplaceAllString(block, "")
+
+	// A loader written by an older version has a stamp matching its own text
+	oldLogic := "\nsource \"$HOME/.config/dotwaifu/shell/shared/core/env.sh\"\n"
+	oldBlock := fmt.Sprintf("%s\n# dotwaifu loader v0 sha256:%s%s%s", integrationStartMarker, loaderChecksum(oldLogic), oldLogic, integrationEndMarker)
+
+	tests := []struct {
+		name    string
+		content string // written to .bashrc unless empty
+		want    Integration
+	}{
+		{"no RC file", "", Integration{Status: IntegrationMissing}},
+		{"RC without a block", "alias g='git'\n", Integration{Status: IntegrationMissing}},
+		{"generated RC", bashRC(t, false), Integration{Status: IntegrationCurrent, Version: LoaderVersion}},
+		{"edited generated RC", bashRC(t, false) + "\nalias g='git'\n", Integration{Status: IntegrationModified, Version: LoaderVersion}},
+		{"appended block", "alias g='git'\n" + bashRC(t, true) + "\nexport A=1\n", Integration{Status: IntegrationCurrent, Version: LoaderVersion}},
+		{"modified block", "alias g='git'\n" + modified + "\n", Integration{Status: IntegrationModified, Version: LoaderVersion}},
+		{"unstamped block", unstamped + "\n", Integration{Status: IntegrationOutdated}},
+		{"older block", oldBlock + "\n", Integration{Status: IntegrationOutdated, Version: 0}},
+		{"missing end marker", "alias g='git'\n" + integrationStartMarker + "\n", Integration{Status: IntegrationMalformed}},
+		{"two blocks", block + "\n" + block + "\n", Integration{Status: IntegrationMalformed}},
+		{"end before start", integrationEndMarker + "\n" + integrationStartMarker + "\n", Integration{Status: IntegrationMalformed}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(GetRCFilePath("bash"))
+			if tt.content != "" {
+				writeRC(t, tt.content)
+			}
+
+			integration, err := CheckIntegration("bash")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if integration != tt.want {
+				t.Errorf("CheckIntegration = %s v%d, want %s v%d", integration.Status, integration.Version, tt.want.Status, tt.want.Version)
+			}
+		})
+	}
+}
+
+func TestRepairedRC(t *testing.T) {
+	setupModules(t, nil)
+	block := bashRC(t, true)
+	modified := strings.Replace(block, integrationEndMarker, "export EDITED=1\n"+integrationEndMarker, 1)
+
+	if _, _, err := RepairedRC("bash"); !os.IsNotExist(err) {
+		t.Errorf("RepairedRC without an RC file: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		content  string
+		repaired string
+	}{
+		{"current block", "alias g='git'\n" + block + "\nexport A=1\n", "alias g='git'\n" + block + "\nexport A=1\n"},
+		{"modified block", "alias g='git'\n" + modified + "\nexport A=1\n", "alias g='git'\n" + block + "\nexport A=1\n"},
+		{"edited generated RC", bashRC(t, false) + "\nalias g='git'\n", bashRC(t, false)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeRC(t, tt.content)
+			current, repaired, err := RepairedRC("bash")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if current != tt.content {
+				t.Errorf("current = %q", current)
+			}
+			if repaired != tt.repaired {
+				t.Errorf("repaired =\n%s\nwant\n%s", repaired, tt.repaired)
+			}
+		})
+	}
+
+	writeRC(t, block+"\n"+block+"\n")
+	if _, _, err := RepairedRC("bash"); err == nil || !strings.Contains(err.Error(), "malformed") {
+		t.Errorf("RepairedRC of a malformed RC file: %v", err)
+	}
+}
+
+// runRC sources the generated bash RC file in a new bash followed by script
+// and returns what that printed on stdout.
+func runRC(t *testing.T, script string) string {
+	t.Helper()
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	writeRC(t, bashRC(t, false))
+
+	cmd := exec.Command("bash", "--norc", "--noprofile", "-c", `source "$HOME/.bashrc"`+"\n"+script)
+	cmd.Env = append(cmd.Environ(), "PATH=/usr/bin:/bin")
+	var out, errOut strings.Builder
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("bash: %v\n%s", err, errOut.String())
+	}
+	return out.String()
+}
+
+func TestFallbackLoader(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh":             "export EDITOR=vim\n",
+		"projects/rust/env.sh":    "export CARGO_HOME=/opt/cargo\n",
+		"projects/rust/notes.md":  "export NOT_A_MODULE=1\n",
+		"projects/flutter/env.sh": "export FLUTTER_ROOT=/opt/flutter\n",
+	})
+
+	// Without a compiled loader every module is sourced directly
+	if got := runRC(t, `echo "$EDITOR $CARGO_HOME ${NOT_A_MODULE-unset}"`); got != "vim /opt/cargo unset\n" {
+		t.Errorf("got %q", got)
+	}
+
+	// DOTWAIFU_SKIP takes modules with or without .sh and whole projects
+	show := `echo "${EDITOR-unset} ${CARGO_HOME-unset} ${FLUTTER_ROOT-unset}"`
+	for skip, want := range map[string]string{
+		"core/env,projects/rust/env.sh": "unset unset /opt/flutter\n",
+		"projects/flutter":              "vim /opt/cargo unset\n",
+		"projects/flu,core/env.s":       "vim /opt/cargo /opt/flutter\n",
+	} {
+		t.Setenv("DOTWAIFU_SKIP", skip)
+		if got := runRC(t, show); got != want {
+			t.Errorf("DOTWAIFU_SKIP=%s: got %q, want %q", skip, got, want)
+		}
+	}
+
+	t.Setenv("DOTWAIFU_SKIP", "")
+	t.Setenv("DOTWAIFU_SAFE", "1")
+	if got := runRC(t, show); got != "unset unset unset\n" {
+		t.Errorf("in safe mode got %q", got)
+	}
+}
@@ -0,0 +1,192 @@
+package shell
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	CategoryPaths   = "paths"
+	CategoryAliases = "aliases"
+	CategoryEnv     = "env"
+	CategoryScripts = "scripts"
+	CategoryMisc    = "misc"
+)
+
+// ImportCategories lists the module every imported statement can go to.
+var ImportCategories = []string{CategoryPaths, CategoryAliases, CategoryEnv, CategoryScripts, CategoryMisc}
+
+// ImportItem is one statement of an RC file, together with the comment lines
+// directly above it, and the module it belongs in.
+type ImportItem struct {
+	Category  string
+	Lines     []string
+	StartLine int
+	EndLine   int
+}
+
+func (i ImportItem) Content() string {
+	return strings.Join(i.Lines, "\n") + "\n"
+}
+
+var (
+	functionPattern = regexp.MustCompile(`^(function\s+[A-Za-z0-9_.:-]+|[A-Za-z0-9_.:-]+\s*\(\s*\))`)
+	logicPattern    = regexp.MustCompile(`^(if|for|while|until|case|eval|source|\.|\[|\[\[|test)(\s|$)`)
+	pathLinePattern = regexp.MustCompile(`^(export\s+)?PATH=`)
+	quotedPattern   = regexp.MustCompile(`'[^']*'|"(\\.|[^"\\])*"`)
+	openerPattern   = regexp.MustCompile(`(^|[\s;])(if|case|do)(\s|$)`)
+	closerPattern   = regexp.MustCompile(`(^|[\s;])(fi|esac|done)(\s|;|$)`)
+)
+
+// blockDepth returns how much a line opens (positive) or closes (negative)
+// compound commands, ignoring quoted text and comments.
+func blockDepth(line string) int {
+	stripped := quotedPattern.ReplaceAllString(line, "''")
+	if idx := strings.Index(stripped, " #"); idx != -1 {
+		stripped = stripped[:idx]
+	}
+	if strings.HasPrefix(strings.TrimSpace(stripped), "#") {
+		return 0
+	}
+
+	depth := strings.Count(stripped, "{") - strings.Count(stripped, "}")
+	depth += len(openerPattern.FindAllString(stripped, -1))
+	depth -= len(closerPattern.FindAllString(stripped, -1))
+	return depth
+}
+
+// ClassifyStatement picks the module a single statement belongs in.
+func ClassifyStatement(lines []string) string {
+	var code []string
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			code = append(code, trimmed)
+		}
+	}
+
+	if len(code) == 0 {
+		return CategoryMisc
+	}
+	if len(code) > 1 || functionPattern.MatchString(code[0]) || logicPattern.MatchString(code[0]) {
+		return CategoryScripts
+	}
+
+	statement := ParseLine(code[0])
+	switch {
+	case statement.Kind == StatementPath || pathLinePattern.MatchString(code[0]):
+		return CategoryPaths
+	case statement.Kind == StatementAlias:
+		return CategoryAliases
+	case statement.Kind == StatementExport:
+		return CategoryEnv
+	case strings.HasPrefix(code[0], "export "):
+		return CategoryEnv
+	case strings.Contains(code[0], "&&") || strings.Contains(code[0], "||"):
+		return CategoryScripts
+	}
+
+	return CategoryMisc
+}
+
+// ClassifyRC splits the contents of an RC file into statements and assigns
+// each one a module. Multi-line functions and compound commands stay in one
+// piece, comments stick to the statement below them and an existing dotwaifu
+// block is left out. Items are returned in file order; their line ranges do
+// not overlap.
+func ClassifyRC(content string) []ImportItem {
+	var items []ImportItem
+	var pending []string
+	pendingStart := 0
+	depth := 0
+	inIntegration := false
+
+	flush := func(end int) {
+		if len(pending) == 0 {
+			return
+		}
+		for strings.TrimSpace(pending[len(pending)-1]) == "" {
+			pending = pending[:len(pending)-1]
+		}
+		items = append(items, ImportItem{
+			Category:  ClassifyStatement(pending),
+			Lines:     pending,
+			StartLine: pendingStart,
+			EndLine:   end,
+		})
+		pending = nil
+	}
+
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	for i, line := range lines {
+		lineNo := i + 1
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == integrationStartMarker:
+			flush(lineNo - 1)
+			inIntegration = true
+			continue
+		case trimmed == integrationEndMarker:
+			inIntegration = false
+			continue
+		case inIntegration:
+			continue
+		case depth == 0 && len(pending) == 0 && strings.HasPrefix(trimmed, "#!"):
+			continue
+		case depth == 0 && trimmed == "":
+			// A comment paragraph usually introduces the statements below
+			// it, so it is carried over to the next statement
+			if len(pending) > 0 {
+				pending = append(pending, line)
+			}
+			continue
+		}
+
+		if len(pending) == 0 {
+			pendingStart = lineNo
+		}
+		pending = append(pending, line)
+
+		depth += blockDepth(line)
+		if depth < 0 {
+			depth = 0
+		}
+
+		continuation := strings.HasSuffix(trimmed, "\\")
+		if depth == 0 && !continuation && !strings.HasPrefix(trimmed, "#") {
+			flush(lineNo)
+		}
+	}
+	flush(len(lines))
+
+	return items
+}
+
+// StripItems returns content without the lines covered by items.
+func StripItems(content string, items []ImportItem) string {
+	remove := make(map[int]bool)
+	for _, item := range items {
+		for line := item.StartLine; line <= item.EndLine; line++ {
+			remove[line] = true
+		}
+	}
+
+	var kept []string
+	for i, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		if remove[i+1] {
+			continue
+		}
+		// Removed statements leave their surrounding blank lines behind
+		if strings.TrimSpace(line) == "" && len(kept) > 0 && strings.TrimSpace(kept[len(kept)-1]) == "" {
+			continue
+		}
+		kept = append(kept, line)
+	}
+
+	result := strings.TrimSpace(strings.Join(kept, "\n"))
+	if result != "" {
+		result += "\n"
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package shell
+
+import (
+	"reflect"
+	"testing"
+)
+
+const sampleRC = `#!/bin/bash
+# Go
+export GOPATH=$HOME/go
+export PATH="$GOPATH/bin:$PATH"
+
+alias ll='ls -la'
+greet() {
+    if [ -n "$1" ]; then
+        echo "hi $1 }"
+    fi
+}
+eval "$(starship init bash)"
+export PATH=$(brew --prefix)/bin:$PATH
+# === dotwaifu Configuration (Added by dotwaifu) ===
+source ~/.config/dotwaifu/state/loader.sh
+# === End dotwaifu Configuration ===
+set -o vi
+export FOO=bar \
+    BAZ=qux
+[ -f ~/.fzf.bash ] && source ~/.fzf.bash
+`
+
+func TestClassifyRC(t *testing.T) {
+	want := []ImportItem{
+		{Category: CategoryEnv, Lines: []string{"# Go", "export GOPATH=$HOME/go"}, StartLine: 2, EndLine: 3},
+		{Category: CategoryPaths, Lines: []string{`export PATH="$GOPATH/bin:$PATH"`}, StartLine: 4, EndLine: 4},
+		{Category: CategoryAliases, Lines: []string{"alias ll='ls -la'"}, StartLine: 6, EndLine: 6},
+		{Category: CategoryScripts, Lines: []string{"greet() {", `    if [ -n "$1" ]; then`, `        echo "hi $1 }"`, "    fi", "}"}, StartLine: 7, EndLine: 11},
+		{Category: CategoryScripts, Lines: []string{`eval "$(starship init bash)"`}, StartLine: 12, EndLine: 12},
+		{Category: CategoryPaths, Lines: []string{"export PATH=$(brew --prefix)/bin:$PATH"}, StartLine: 13, EndLine: 13},
+		{Category: CategoryMisc, Lines: []string{"set -o vi"}, StartLine: 17, EndLine: 17},
+		{Category: CategoryScripts, Lines: []string{`export FOO=bar \`, "    BAZ=qux"}, StartLine: 18, EndLine: 19},
+		{Category: CategoryScripts, Lines: []string{"[ -f ~/.fzf.bash ] && source ~/.fzf.bash"}, StartLine: 20, EndLine: 20},
+	}
+
+	got := ClassifyRC(sampleRC)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ClassifyRC =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestClassifyRCCommentParagraph(t *testing.T) {
+	got := ClassifyRC("# Editor settings\n\nexport EDITOR=vim\n# trailing note\n")
+	want := []ImportItem{
+		{Category: CategoryEnv, Lines: []string{"# Editor settings", "", "export EDITOR=vim"}, StartLine: 1, EndLine: 3},
+		{Category: CategoryMisc, Lines: []string{"# trailing note"}, StartLine: 4, EndLine: 4},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ClassifyRC =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestClassifyStatement(t *testing.T) {
+	tests := map[string]string{
+		"alias g=git":                     CategoryAliases,
+		"export EDITOR=vim":               CategoryEnv,
+		"export EDITOR":                   CategoryEnv,
+		"PATH=~/bin:$PATH":                CategoryPaths,
+		"export PATH=/opt/bin":            CategoryPaths,
+		"function mkcd { mkdir -p $1; }":  CategoryScripts,
+		"mkcd() { mkdir -p $1; }":         CategoryScripts,
+		"source ~/.cargo/env":             CategoryScripts,
+		". ~/.cargo/env":                  CategoryScripts,
+		"command -v nvim && alias v=nvim": CategoryScripts,
+		"shopt -s histappend":             CategoryMisc,
+		"# only a comment":                CategoryMisc,
+	}
+
+	for line, want := range tests {
+		if got := ClassifyStatement([]string{line}); got != want {
+			t.Errorf("ClassifyStatement(%q) = %s, want %s", line, got, want)
+		}
+	}
+}
+
+func TestStripItems(t *testing.T) {
+	items := ClassifyRC(sampleRC)
+	var kept []ImportItem
+	for _, item := range items {
+		if item.Category != CategoryMisc {
+			kept = append(kept, item)
+		}
+	}
+
+	want := `#!/bin/bash
+
+# === dotwaifu Configuration (Added by dotwaifu) ===
+source ~/.config/dotwaifu/state/loader.sh
+# === End dotwaifu Configuration ===
+set -o vi
+`
+	if got := StripItems(sampleRC, kept); got != want {
+		t.Errorf("StripItems =\n%s\nwant\n%s", got, want)
+	}
+}
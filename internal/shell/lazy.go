@@ -0,0 +1,155 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var triggerPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.:-]*$`)
+
+// lazyGroup is a set of modules that is only sourced the first time one of
+// its trigger commands runs: a single module with a lazy= directive, or every
+// module of a project with lazy triggers in its project.yaml.
+type lazyGroup struct {
+	Name     string
+	Triggers []string
+	Modules  []Module
+}
+
+// LazyTriggers returns the commands that load module on first use, or nil if
+// it is loaded right away.
+func (m Module) LazyTriggers() ([]string, error) {
+	triggers, _, err := lazyTriggers(m)
+	return triggers, err
+}
+
+// lazyTriggers returns the lazy triggers of module along with the name of
+// the group it loads with. Triggers in project.yaml make the whole project
+// one group and take precedence over a lazy= directive in the module itself.
+func lazyTriggers(m Module) ([]string, string, error) {
+	if m.Project != "" {
+		manifest, err := LoadProjectManifest(m.Project)
+		if err != nil {
+			return nil, "", fmt.Errorf("%s: %w", GetProjectManifestPath(m.Project), err)
+		}
+		if len(manifest.Lazy) > 0 {
+			return manifest.Lazy, "projects/" + m.Project, nil
+		}
+	}
+
+	content, err := os.ReadFile(m.Path)
+	if err != nil {
+		return nil, "", err
+	}
+	return ParseDirectives(string(content))["lazy"], m.RelPath(), nil
+}
+
+// lazyGroups splits modules into those loaded right away and the lazy
+// groups, keeping the order of both.
+func lazyGroups(modules []Module) ([]Module, []lazyGroup, error) {
+	var eager []Module
+	var groups []lazyGroup
+	index := make(map[string]int)
+	owner := make(map[string]string)
+
+	for _, module := range modules {
+		triggers, name, err := lazyTriggers(module)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(triggers) == 0 {
+			eager = append(eager, module)
+			continue
+		}
+
+		i, ok := index[name]
+		if !ok {
+			for _, trigger := range triggers {
+				if !triggerPattern.MatchString(trigger) {
+					return nil, nil, fmt.Errorf("%s: lazy trigger %q is not a valid command name", name, trigger)
+				}
+				if other, taken := owner[trigger]; taken {
+					return nil, nil, fmt.Errorf("%s and %s both load lazily on %s", other, name, trigger)
+				}
+				owner[trigger] = name
+			}
+
+			i = len(groups)
+			index[name] = i
+			groups = append(groups, lazyGroup{Name: name, Triggers: triggers})
+		}
+		groups[i].Modules = append(groups[i].Modules, module)
+	}
+
+	return eager, groups, nil
+}
+
+// writeModules writes the loading of modules in the given order. Lazy modules
+// get stub functions instead, see writeLazyGroup. With inlineShell set, the
+// other modules are copied into the script instead of sourced.
+func writeModules(out *strings.Builder, modules []Module, inlineShell string) error {
+	eager, groups, err := lazyGroups(modules)
+	if err != nil {
+		return err
+	}
+
+	lazy := make(map[string]string)
+	for _, group := range groups {
+		for _, module := range group.Modules {
+			lazy[module.RelPath()] = group.Name
+		}
+	}
+	for _, module := range eager {
+		after, err := module.After()
+		if err != nil {
+			return err
+		}
+		for _, target := range after {
+			if name, ok := lazy[target]; ok {
+				return fmt.Errorf("%s: cannot load after %s, which %s loads lazily", module.RelPath(), target, name)
+			}
+		}
+	}
+
+	if inlineShell != "" {
+		if err := inlineModules(out, eager, inlineShell); err != nil {
+			return err
+		}
+	} else {
+		sourceList(out, "", eager)
+	}
+
+	for _, group := range groups {
+		writeLazyGroup(out, group)
+	}
+	return nil
+}
+
+// writeLazyGroup writes a loader function for group and a stub for each
+// trigger. The first call of a stub removes all stubs of the group, sources
+// its modules and then runs the command again, which now resolves to the
+// function the modules defined or to the binary on PATH. When DOTWAIFU_SKIP
+// leaves out every module of the group, no stubs are defined, so that the
+// triggers run as if the modules did not exist.
+func writeLazyGroup(out *strings.Builder, group lazyGroup) {
+	loader := "_dotwaifu_lazy_" + projectIdent(strings.TrimSuffix(group.Name, ".sh"))
+
+	fmt.Fprintf(out, "\n# %s is loaded on first use of %s\n", group.Name, strings.Join(group.Triggers, ", "))
+	fmt.Fprintf(out, "%s() {\n", loader)
+	fmt.Fprintf(out, "    unset -f %s\n", strings.Join(group.Triggers, " "))
+	sourceList(out, "    ", group.Modules)
+	out.WriteString("    _dotwaifu_save_status\n")
+	out.WriteString("}\n")
+
+	var skipped []string
+	for _, module := range group.Modules {
+		skipped = append(skipped, `_dotwaifu_skip "$DOTWAIFU_CONFIG_ROOT"/shell/shared/`+shellQuote(module.RelPath()))
+	}
+	fmt.Fprintf(out, "if [ -z \"$DOTWAIFU_SKIP\" ] || ! { %s; }; then\n", strings.Join(skipped, " && "))
+	for _, trigger := range group.Triggers {
+		fmt.Fprintf(out, "    function %s { %s; %s \"$@\"; }\n", trigger, loader, trigger)
+	}
+	out.WriteString("fi\n")
+}
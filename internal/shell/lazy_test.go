@@ -0,0 +1,119 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLazyStubs(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/paths.sh": "export PATH=\"$HOME/bin:$PATH\"\n",
+		"core/nvm.sh":   "# dotwaifu: lazy=nvm,nvm_exec\nexport NVM_LOADED=1\nnvm() { echo \"nvm $*\"; }\n",
+		"core/tool.sh":  "# dotwaifu: lazy=mytool\nexport TOOL_HOME=/opt/tool\n",
+	})
+
+	// A trigger that is a binary on PATH rather than a function of the module
+	home, _ := os.UserHomeDir()
+	tool := filepath.Join(home, "bin", "mytool")
+	if err := os.MkdirAll(filepath.Dir(tool), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tool, []byte("#!/bin/sh\necho \"mytool $* $TOOL_HOME\"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got := runLoader(t, `echo "${NVM_LOADED-unset} $(type -t nvm) $(type -t nvm_exec)"
+nvm use 18
+nvm ls
+echo "$NVM_LOADED $(type -t nvm) $(type -t nvm_exec)"
+mytool build
+mytool test`)
+
+	want := []string{
+		"unset function function",
+		"nvm use 18",
+		"nvm ls",
+		"1 function ",
+		"mytool build /opt/tool",
+		"mytool test /opt/tool",
+	}
+	if got != strings.Join(want, "\n")+"\n" {
+		t.Errorf("got\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestLazyProject(t *testing.T) {
+	setupModules(t, map[string]string{
+		"projects/flutter/env.sh":     "export FLUTTER_ROOT=/opt/flutter\n",
+		"projects/flutter/scripts.sh": "fl() { echo \"fl $* $FLUTTER_ROOT\"; }\n",
+	})
+	if err := SaveProjectManifest("flutter", &ProjectManifest{Lazy: []string{"fl"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := runLoader(t, `echo "${FLUTTER_ROOT-unset}"
+fl run
+echo "$FLUTTER_ROOT"`)
+	if got != "unset\nfl run /opt/flutter\n/opt/flutter\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestLazyGroupErrors(t *testing.T) {
+	tests := map[string]map[string]string{
+		"is not a valid command name": {
+			"core/nvm.sh": "# dotwaifu: lazy=nvm;rm\n",
+		},
+		"both load lazily on nvm": {
+			"core/nvm.sh":          "# dotwaifu: lazy=nvm\n",
+			"projects/node/env.sh": "# dotwaifu: lazy=nvm\n",
+		},
+		"cannot load after core/nvm.sh": {
+			"core/nvm.sh":  "# dotwaifu: lazy=nvm\n",
+			"core/node.sh": "# dotwaifu: after=nvm\n",
+		},
+	}
+
+	for want, modules := range tests {
+		setupModules(t, modules)
+		if _, err := LoaderScript(nil); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoaderScript with %v: %v, want an error containing %q", modules, err, want)
+		}
+	}
+}
+
+func TestLazyStubsSkipped(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/nvm.sh":                 "# dotwaifu: lazy=nvm\nnvm() { echo nvm; }\n",
+		"core/tool.sh":                "# dotwaifu: lazy=mytool\nmytool() { echo mytool; }\n",
+		"projects/flutter/env.sh":     "export FLUTTER_ROOT=/opt/flutter\n",
+		"projects/flutter/scripts.sh": "fl() { echo fl; }\n",
+	})
+	if err := SaveProjectManifest("flutter", &ProjectManifest{Lazy: []string{"fl"}}); err != nil {
+		t.Fatal(err)
+	}
+	show := `echo "[$(type -t nvm)] [$(type -t mytool)] [$(type -t fl)]"`
+
+	// A group gets its stubs as long as one of its modules is loaded
+	tests := map[string]string{
+		"":                         "[function] [function] [function]\n",
+		"core/nvm":                 "[] [function] [function]\n",
+		"core/tool.sh,core/nvm.sh": "[] [] [function]\n",
+		"projects/flutter":         "[function] [function] []\n",
+		"projects/flutter/env":     "[function] [function] [function]\n",
+	}
+	for skip, want := range tests {
+		t.Setenv("DOTWAIFU_SKIP", skip)
+		if got := runLoader(t, show); got != want {
+			t.Errorf("DOTWAIFU_SKIP=%s: got %q, want %q", skip, got, want)
+		}
+	}
+
+	t.Setenv("DOTWAIFU_SKIP", "")
+	t.Setenv("DOTWAIFU_SAFE", "1")
+	if got := runLoader(t, show); got != "[] [] []\n" {
+		t.Errorf("in safe mode got %q", got)
+	}
+}
@@ -0,0 +1,299 @@
+package shell
+
+import (
+	"dotwaifu/internal/config"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// LintRule is one of the checks of 'dotwaifu lint'.
+type LintRule struct {
+	Name        string
+	Description string
+	// Severity applies unless the lint section of config.yaml overrides it
+	Severity string
+}
+
+// LintRules are the checks in the order they are documented.
+var LintRules = []LintRule{
+	{Name: "duplicate-alias", Description: "an alias is defined more than once, in core or projects", Severity: config.SeverityWarning},
+	{Name: "alias-shadows-binary", Description: "an alias hides a command of the same name on PATH", Severity: config.SeverityWarning},
+	{Name: "missing-path", Description: "a directory added to PATH does not exist", Severity: config.SeverityWarning},
+	{Name: "duplicate-path", Description: "a directory is added to PATH more than once", Severity: config.SeverityWarning},
+	{Name: "duplicate-env", Description: "a variable is set in more than one module", Severity: config.SeverityWarning},
+	{Name: "wrong-category", Description: "an alias, variable or PATH change is in the module of another type", Severity: config.SeverityWarning},
+}
+
+// LookupLintRule returns the rule called name.
+func LookupLintRule(name string) (LintRule, bool) {
+	for _, rule := range LintRules {
+		if rule.Name == name {
+			return rule, true
+		}
+	}
+	return LintRule{}, false
+}
+
+// LintIssue is a problem the lint checks found in a module.
+type LintIssue struct {
+	Rule     string
+	Severity string
+	Module   string
+	Line     int
+	Message  string
+}
+
+func (i LintIssue) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s [%s]", i.Module, i.Line, i.Severity, i.Message, i.Rule)
+}
+
+// lintSource is a module along with the text to check for it.
+type lintSource struct {
+	Module  Module
+	Content string
+}
+
+// lintSite is where a name was first defined.
+type lintSite struct {
+	Module string
+	Line   int
+}
+
+// describe refers to the site from a statement in module.
+func (s lintSite) describe(module string) string {
+	if s.Module == module {
+		return fmt.Sprintf("on line %d", s.Line)
+	}
+	return fmt.Sprintf("in %s:%d", s.Module, s.Line)
+}
+
+// linter carries what earlier modules defined, for the checks that span
+// modules.
+type linter struct {
+	cfg     *config.Config
+	issues  []LintIssue
+	aliases map[string]lintSite
+	entries map[string]lintSite
+	vars    map[string]lintSite
+	// values are the variables set by the modules so far, for expanding
+	// PATH entries
+	values map[string]string
+}
+
+// Lint checks the modules of core and the enabled projects in load order,
+// see LintRules. Modules with unresolved sync conflicts are skipped.
+func Lint(cfg *config.Config) ([]LintIssue, error) {
+	return lint(cfg, nil)
+}
+
+// LintModule checks content as the new text of module against the other
+// modules as they are on disk, and returns the issues in module.
+func LintModule(cfg *config.Config, module Module, content string) ([]LintIssue, error) {
+	issues, err := lint(cfg, &lintSource{Module: module, Content: content})
+	if err != nil {
+		return nil, err
+	}
+
+	var own []LintIssue
+	for _, issue := range issues {
+		if issue.Module == module.RelPath() {
+			own = append(own, issue)
+		}
+	}
+	return own, nil
+}
+
+func lint(cfg *config.Config, edited *lintSource) ([]LintIssue, error) {
+	modules, err := LoadOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	var sources []lintSource
+	for _, module := range modules {
+		if edited != nil && module.RelPath() == edited.Module.RelPath() {
+			sources = append(sources, *edited)
+			edited = nil
+			continue
+		}
+		if module.HasConflict() {
+			continue
+		}
+
+		content, err := os.ReadFile(module.Path)
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, lintSource{Module: module, Content: string(content)})
+	}
+	if edited != nil {
+		sources = append(sources, *edited)
+	}
+
+	l := &linter{
+		cfg:     cfg,
+		aliases: make(map[string]lintSite),
+		entries: make(map[string]lintSite),
+		vars:    make(map[string]lintSite),
+		values:  make(map[string]string),
+	}
+	for _, source := range sources {
+		l.check(source)
+	}
+	return l.issues, nil
+}
+
+func (l *linter) report(rule, module string, line int, format string, args ...interface{}) {
+	defaultRule, _ := LookupLintRule(rule)
+	severity := l.cfg.LintSeverity(rule, defaultRule.Severity)
+	if severity == config.SeverityOff {
+		return
+	}
+	l.issues = append(l.issues, LintIssue{
+		Rule:     rule,
+		Severity: severity,
+		Module:   module,
+		Line:     line,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (l *linter) check(source lintSource) {
+	rel := source.Module.RelPath()
+
+	for _, statement := range ParseModule(source.Content) {
+		// Only the built-in types say what belongs in them
+		switch source.Module.Type {
+		case CategoryPaths, CategoryAliases, CategoryEnv:
+			if category := statementCategory(statement); category != "" && category != source.Module.Type {
+				l.report("wrong-category", rel, statement.Line, "%s belongs in %s.sh", describeStatement(statement), category)
+			}
+		}
+
+		switch statement.Kind {
+		case StatementAlias:
+			l.checkAlias(rel, statement)
+		case StatementPath:
+			l.checkPath(rel, statement)
+		case StatementExport:
+			l.checkVariable(rel, statement)
+		}
+	}
+}
+
+func (l *linter) checkAlias(rel string, statement Statement) {
+	site := lintSite{Module: rel, Line: statement.Line}
+	if first, ok := l.aliases[statement.Name]; ok {
+		l.report("duplicate-alias", rel, statement.Line, "alias %s is already defined %s", statement.Name, first.describe(rel))
+	} else {
+		l.aliases[statement.Name] = site
+	}
+
+	// alias ls="ls --color" extends the command rather than hiding it
+	words := strings.Fields(statement.Value)
+	if len(words) > 0 && (words[0] == statement.Name || words[0] == "command" || words[0] == `\`+statement.Name) {
+		return
+	}
+	if strings.Contains(statement.Name, "/") {
+		return
+	}
+	if binary, err := exec.LookPath(statement.Name); err == nil {
+		l.report("alias-shadows-binary", rel, statement.Line, "alias %s hides %s", statement.Name, binary)
+	}
+}
+
+func (l *linter) checkPath(rel string, statement Statement) {
+	for _, entry := range statement.Entries {
+		if first, ok := l.entries[entry]; ok {
+			l.report("duplicate-path", rel, statement.Line, "%s is already added to PATH %s", entry, first.describe(rel))
+			continue
+		}
+		l.entries[entry] = lintSite{Module: rel, Line: statement.Line}
+
+		// Entries built from variables nobody set cannot be checked
+		dir, ok := l.expand(entry, statement.Quote)
+		if !ok || !filepath.IsAbs(dir) {
+			continue
+		}
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			if dir == entry {
+				l.report("missing-path", rel, statement.Line, "%s does not exist", entry)
+			} else {
+				l.report("missing-path", rel, statement.Line, "%s (%s) does not exist", entry, dir)
+			}
+		}
+	}
+}
+
+func (l *linter) checkVariable(rel string, statement Statement) {
+	if value, ok := l.expand(statement.Value, statement.Quote); ok {
+		l.values[statement.Name] = value
+	}
+
+	// FPATH="$HOME/.zfunc:$FPATH" adds to the variable instead of setting it
+	if strings.Contains(statement.Value, "$"+statement.Name) || strings.Contains(statement.Value, "${"+statement.Name+"}") {
+		return
+	}
+
+	first, ok := l.vars[statement.Name]
+	switch {
+	case !ok:
+		l.vars[statement.Name] = lintSite{Module: rel, Line: statement.Line}
+	case first.Module != rel:
+		l.report("duplicate-env", rel, statement.Line, "%s is already set %s", statement.Name, first.describe(rel))
+	}
+}
+
+// expand resolves the variables in value from what the modules set so far
+// and then from the environment dotwaifu runs in.
+func (l *linter) expand(value string, quote byte) (string, bool) {
+	parts, ok := ValueParts(value, quote)
+	if !ok {
+		return "", false
+	}
+
+	var out strings.Builder
+	for _, part := range parts {
+		if part.Variable == "" {
+			out.WriteString(part.Literal)
+			continue
+		}
+		resolved, ok := l.values[part.Variable]
+		if !ok {
+			resolved, ok = os.LookupEnv(part.Variable)
+		}
+		if !ok {
+			return "", false
+		}
+		out.WriteString(resolved)
+	}
+	return out.String(), true
+}
+
+// statementCategory returns the built-in config type a simple statement
+// belongs in, or "" if it can go anywhere.
+func statementCategory(statement Statement) string {
+	switch statement.Kind {
+	case StatementPath:
+		return CategoryPaths
+	case StatementAlias:
+		return CategoryAliases
+	case StatementExport:
+		return CategoryEnv
+	}
+	return ""
+}
+
+func describeStatement(statement Statement) string {
+	switch statement.Kind {
+	case StatementPath:
+		return "PATH change"
+	case StatementAlias:
+		return "alias " + statement.Name
+	default:
+		return "variable " + statement.Name
+	}
+}
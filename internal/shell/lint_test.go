@@ -0,0 +1,116 @@
+package shell
+
+import (
+	"dotwaifu/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// setupLintModules writes modules with one problem of every kind and returns
+// the directory on PATH, which holds a "tool" binary.
+func setupLintModules(t *testing.T) string {
+	t.Helper()
+	setupModules(t, map[string]string{
+		"core/aliases.sh": "alias g=git\nalias tool='echo x'\nalias ls='ls --color'\nexport EDITOR=vim\n",
+		"core/env.sh":     "export EDITOR=nvim\nexport BIN=\"$HOME/bin\"\nexport FPATH=\"$HOME/.zfunc:$FPATH\"\n",
+		"core/paths.sh": "export PATH=\"$BIN:$PATH\"\nexport PATH=\"/nonexistent/dotwaifu:$PATH\"\n" +
+			"export PATH=\"$BIN:$PATH\"\nexport PATH=\"$DOTWAIFU_UNSET/bin:$PATH\"\nexport PATH=\"$HOME/go/bin:$PATH\"\n",
+		"projects/work/aliases.sh": "alias g=git\n",
+	})
+
+	home, _ := os.UserHomeDir()
+	if err := os.Mkdir(filepath.Join(home, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "tool"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir)
+	return binDir
+}
+
+func lintStrings(issues []LintIssue) string {
+	var lines []string
+	for _, issue := range issues {
+		lines = append(lines, issue.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestLint(t *testing.T) {
+	binDir := setupLintModules(t)
+	home, _ := os.UserHomeDir()
+
+	issues, err := Lint(&config.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := strings.Join([]string{
+		"core/aliases.sh:2: warning: alias tool hides " + filepath.Join(binDir, "tool") + " [alias-shadows-binary]",
+		"core/aliases.sh:4: warning: variable EDITOR belongs in env.sh [wrong-category]",
+		"core/env.sh:1: warning: EDITOR is already set in core/aliases.sh:4 [duplicate-env]",
+		"core/paths.sh:2: warning: /nonexistent/dotwaifu does not exist [missing-path]",
+		"core/paths.sh:3: warning: $BIN is already added to PATH on line 1 [duplicate-path]",
+		"core/paths.sh:5: warning: $HOME/go/bin (" + home + "/go/bin) does not exist [missing-path]",
+		"projects/work/aliases.sh:1: warning: alias g is already defined in core/aliases.sh:1 [duplicate-alias]",
+	}, "\n")
+	if got := lintStrings(issues); got != want {
+		t.Errorf("Lint =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLintSeverityOverrides(t *testing.T) {
+	setupLintModules(t)
+	cfg := &config.Config{Lint: map[string]string{
+		"missing-path":         config.SeverityOff,
+		"alias-shadows-binary": config.SeverityOff,
+		"duplicate-alias":      config.SeverityOff,
+		"duplicate-path":       config.SeverityOff,
+		"duplicate-env":        config.SeverityError,
+		"wrong-category":       config.SeverityInfo,
+	}}
+
+	issues, err := Lint(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "core/aliases.sh:4: info: variable EDITOR belongs in env.sh [wrong-category]\n" +
+		"core/env.sh:1: error: EDITOR is already set in core/aliases.sh:4 [duplicate-env]"
+	if got := lintStrings(issues); got != want {
+		t.Errorf("Lint =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestLintModule(t *testing.T) {
+	setupLintModules(t)
+	module := Module{Type: CategoryAliases, Path: filepath.Join(GetCoreDir(), "aliases.sh")}
+
+	// The edited copy replaces the module on disk, so its old problems are gone
+	issues, err := LintModule(&config.Config{}, module, "alias g=git\nalias gs='git status'\nalias gs='git status -s'\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "core/aliases.sh:3: warning: alias gs is already defined on line 2 [duplicate-alias]"
+	if got := lintStrings(issues); got != want {
+		t.Errorf("LintModule =\n%s\nwant\n%s", got, want)
+	}
+
+	// A new module is checked after the others
+	newModule := Module{Project: "work", Type: CategoryEnv, Path: filepath.Join(GetProjectsDir(), "work", "env.sh")}
+	issues, err = LintModule(&config.Config{}, newModule, "export BIN=/usr/local/bin\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want = "projects/work/env.sh:1: warning: BIN is already set in core/env.sh:2 [duplicate-env]"
+	if got := lintStrings(issues); got != want {
+		t.Errorf("LintModule =\n%s\nwant\n%s", got, want)
+	}
+}
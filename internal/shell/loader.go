@@ -0,0 +1,525 @@
+package shell
+
+import (
+	"dotwaifu/internal/config"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var (
+	functionNamePattern = regexp.MustCompile(`^\s*(?:function\s+([A-Za-z0-9_.:-]+)|([A-Za-z0-9_.:-]+)\s*\(\s*\))`)
+	identifierUnsafe    = regexp.MustCompile(`[^A-Za-z0-9_]`)
+)
+
+// GetLoaderPath is the compiled POSIX loader that the RC block sources.
+func GetLoaderPath() string {
+	return filepath.Join(config.GetStateDir(), "loader.sh")
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// shellPattern renders a glob as a case pattern in which only * and ? are
+// special.
+func shellPattern(glob string) string {
+	var out, literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			out.WriteString(shellQuote(literal.String()))
+			literal.Reset()
+		}
+	}
+
+	for _, r := range glob {
+		if r == '*' || r == '?' {
+			flush()
+			out.WriteRune(r)
+			continue
+		}
+		literal.WriteRune(r)
+	}
+	flush()
+
+	return out.String()
+}
+
+// dirPattern renders an activation directory glob as a case pattern for
+// "$PWD/", so that the directory matches itself and everything below it.
+func dirPattern(glob string) string {
+	glob = strings.TrimSuffix(glob, "/")
+	switch {
+	case glob == "~":
+		return `"$HOME"/*`
+	case strings.HasPrefix(glob, "~/"):
+		return `"$HOME"` + shellPattern(glob[1:]+"/") + "*"
+	case strings.HasPrefix(glob, "/"):
+		return shellPattern(glob+"/") + "*"
+	default:
+		return "*/" + shellPattern(glob+"/") + "*"
+	}
+}
+
+// projectIdent turns a project name into something usable in shell function
+// and variable names.
+func projectIdent(project string) string {
+	return identifierUnsafe.ReplaceAllString(project, "_")
+}
+
+// projectSymbols are the names a project's modules define, which have to be
+// restored when the project is unloaded. PATH is handled separately.
+type projectSymbols struct {
+	Vars      []string
+	Aliases   []string
+	Functions []string
+}
+
+func collectSymbols(modules []Module) (projectSymbols, error) {
+	var symbols projectSymbols
+	seen := make(map[string]bool)
+	add := func(list *[]string, kind, name string) {
+		if !seen[kind+name] {
+			seen[kind+name] = true
+			*list = append(*list, name)
+		}
+	}
+
+	for _, module := range modules {
+		content, err := os.ReadFile(module.Path)
+		if err != nil {
+			return symbols, err
+		}
+
+		for _, statement := range ParseModule(string(content)) {
+			switch statement.Kind {
+			case StatementExport:
+				add(&symbols.Vars, "var", statement.Name)
+			case StatementAlias:
+				add(&symbols.Aliases, "alias", statement.Name)
+			case StatementOther:
+				if match := functionNamePattern.FindStringSubmatch(statement.Raw); match != nil {
+					add(&symbols.Functions, "function", match[1]+match[2])
+				}
+			}
+		}
+	}
+
+	return symbols, nil
+}
+
+// sourceList writes a loop sourcing modules in the given order, skipping
+// those with unresolved sync conflicts or listed in DOTWAIFU_SKIP. Failures are caught by
+// _dotwaifu_check, see statusHelpers.
+func sourceList(out *strings.Builder, indent string, modules []Module) {
+	if len(modules) == 0 {
+		return
+	}
+
+	var files []string
+	for _, module := range modules {
+		files = append(files, `"$DOTWAIFU_CONFIG_ROOT"/shell/shared/`+shellQuote(module.RelPath()))
+	}
+
+	fmt.Fprintf(out, "%sfor config in %s; do\n", indent, strings.Join(files, " \\\n"+indent+"    "))
+	fmt.Fprintf(out, "%s    [ -e \"$DOTWAIFU_CONFIG_ROOT/state/conflicts/${config#\"$DOTWAIFU_CONFIG_ROOT\"/}\" ] && continue\n", indent)
+	fmt.Fprintf(out, "%s    [ -n \"$DOTWAIFU_SKIP\" ] && _dotwaifu_skip \"$config\" && continue\n", indent)
+	fmt.Fprintf(out, "%s    [ -r \"$config\" ] || continue\n", indent)
+	fmt.Fprintf(out, "%s    source \"$config\" 2>\"$_dotwaifu_stderr\"\n", indent)
+	fmt.Fprintf(out, "%s    _dotwaifu_check \"$config\" $?\n", indent)
+	fmt.Fprintf(out, "%sdone\n", indent)
+}
+
+// inlineModules copies modules into the script in the given order, each in a
+// brace group with its stderr redirected so that _dotwaifu_check can tell
+// whether it failed. A syntax error or a top-level return would end the whole
+// script, so modules that fail the syntax check of shell or may return early
+// are sourced instead. So are modules with aliases meant for their own code,
+// as the shell parses a brace group before it defines them. Modules with
+// unresolved sync conflicts are left out.
+func inlineModules(out *strings.Builder, modules []Module, shell string) error {
+	// Errors in inlined code name the script they were inlined into
+	if shell == "zsh" {
+		out.WriteString("_dotwaifu_script=${(%):-%x}\n")
+	} else {
+		out.WriteString("_dotwaifu_script=${BASH_SOURCE[0]}\n")
+	}
+
+	for _, module := range modules {
+		if module.HasConflict() {
+			continue
+		}
+
+		if err := CheckSyntax(shell, module.Path); err != nil {
+			fmt.Fprintf(out, "\n# --- %s (sourced, it does not parse) ---\n", module.RelPath())
+			sourceList(out, "", []Module{module})
+			continue
+		}
+
+		content, err := os.ReadFile(module.Path)
+		if err != nil {
+			return err
+		}
+
+		reason := ""
+		switch {
+		case hasTopLevelReturn(string(content)):
+			reason = "it may return early"
+		case usesOwnAliases(string(content)):
+			reason = "it uses its own aliases"
+		}
+		if reason != "" {
+			fmt.Fprintf(out, "\n# --- %s (sourced, %s) ---\n", module.RelPath(), reason)
+			sourceList(out, "", []Module{module})
+			continue
+		}
+
+		// The : keeps the group valid when the module has no commands
+		fmt.Fprintf(out, "\n# --- %s ---\n_dotwaifu_start=$LINENO; { :\n%s", module.RelPath(), content)
+		if len(content) > 0 && content[len(content)-1] != '\n' {
+			out.WriteString("\n")
+		}
+		out.WriteString("} 2>\"$_dotwaifu_stderr\"\n")
+		fmt.Fprintf(out, "_dotwaifu_check \"$DOTWAIFU_CONFIG_ROOT\"/shell/shared/%s $? \"$_dotwaifu_script\" \"$_dotwaifu_start\"\n", shellQuote(module.RelPath()))
+	}
+	return nil
+}
+
+// usesOwnAliases reports whether content defines an alias before code other
+// than aliases, variables and PATH changes, which might use it.
+func usesOwnAliases(content string) bool {
+	defined := false
+	for _, statement := range ParseModule(content) {
+		switch {
+		case statement.Kind == StatementAlias:
+			defined = true
+		case statement.Kind != StatementOther:
+		case strings.HasPrefix(strings.TrimSpace(statement.Raw), "alias "):
+			defined = true
+		case defined:
+			return true
+		}
+	}
+	return false
+}
+
+const activationHelpers = `# Helpers for projects that are only active in matching directories
+
+_dotwaifu_has_marker() {
+    _dotwaifu_dir=$PWD
+    while [ -n "$_dotwaifu_dir" ] && [ "$_dotwaifu_dir" != "$HOME" ]; do
+        [ -e "$_dotwaifu_dir/$1" ] && return 0
+        _dotwaifu_dir=${_dotwaifu_dir%/*}
+    done
+    return 1
+}
+
+_dotwaifu_load_remotes() {
+    [ "$_dotwaifu_remotes_pwd" = "$PWD" ] && return 0
+    _dotwaifu_remotes_pwd=$PWD
+    _dotwaifu_remotes=" $(git config --get-regexp '^remote\..*\.url$' 2>/dev/null | while read -r _dotwaifu_key _dotwaifu_url; do printf '%s ' "$_dotwaifu_url"; done)"
+}
+
+_dotwaifu_save_var() {
+    if eval "[ -n \"\${$2+set}\" ]"; then
+        eval "$1=\"set:\$$2\""
+    else
+        eval "$1=unset"
+    fi
+}
+
+_dotwaifu_restore_var() {
+    eval "_dotwaifu_value=\$$1"
+    case "$_dotwaifu_value" in
+        set:*) eval "$2=\${_dotwaifu_value#set:}" ;;
+        *) unset "$2" ;;
+    esac
+    unset "$1"
+}
+
+_dotwaifu_save_alias() {
+    if _dotwaifu_def=$(alias -- "$2" 2>/dev/null); then
+        case "$_dotwaifu_def" in
+            "alias "*) ;;
+            *) _dotwaifu_def="alias $_dotwaifu_def" ;;
+        esac
+        eval "$1=\$_dotwaifu_def"
+    else
+        eval "$1="
+    fi
+}
+
+_dotwaifu_restore_alias() {
+    eval "_dotwaifu_def=\$$1"
+    unalias -- "$2" 2>/dev/null
+    [ -n "$_dotwaifu_def" ] && eval "$_dotwaifu_def"
+    unset "$1"
+}
+
+# Removes the PATH entries that are in $2 (PATH after loading) but not in $1
+# (PATH before loading)
+_dotwaifu_path_remove_added() {
+    _dotwaifu_rest="$PATH:"
+    _dotwaifu_path=""
+    while [ -n "$_dotwaifu_rest" ]; do
+        _dotwaifu_entry=${_dotwaifu_rest%%:*}
+        _dotwaifu_rest=${_dotwaifu_rest#*:}
+        case ":$2:" in
+            *":$_dotwaifu_entry:"*)
+                case ":$1:" in
+                    *":$_dotwaifu_entry:"*) ;;
+                    *) continue ;;
+                esac
+                ;;
+        esac
+        _dotwaifu_path="${_dotwaifu_path:+$_dotwaifu_path:}$_dotwaifu_entry"
+    done
+    PATH=$_dotwaifu_path
+}
+
+_dotwaifu_forget_project() {
+    _dotwaifu_rest="$DOTWAIFU_ACTIVE_PROJECTS "
+    DOTWAIFU_ACTIVE_PROJECTS=""
+    while [ -n "$_dotwaifu_rest" ]; do
+        _dotwaifu_entry=${_dotwaifu_rest%% *}
+        _dotwaifu_rest=${_dotwaifu_rest#* }
+        [ -n "$_dotwaifu_entry" ] && [ "$_dotwaifu_entry" != "$1" ] &&
+            DOTWAIFU_ACTIVE_PROJECTS="${DOTWAIFU_ACTIVE_PROJECTS:+$DOTWAIFU_ACTIVE_PROJECTS }$_dotwaifu_entry"
+    done
+}
+`
+
+// writeActivation writes the match, load and unload functions of a project
+// that is only active in matching directories.
+func writeActivation(out *strings.Builder, project string, activation Activation, modules []Module, symbols projectSymbols) {
+	ident := projectIdent(project)
+	name := shellQuote(project)
+
+	fmt.Fprintf(out, "\n# %s\n", project)
+	fmt.Fprintf(out, "_dotwaifu_match_%s() {\n", ident)
+	if len(activation.Dirs) > 0 {
+		out.WriteString("    case \"$PWD/\" in\n")
+		for _, dir := range activation.Dirs {
+			fmt.Fprintf(out, "        %s) return 0 ;;\n", dirPattern(dir))
+		}
+		out.WriteString("    esac\n")
+	}
+	for _, marker := range activation.Markers {
+		fmt.Fprintf(out, "    _dotwaifu_has_marker %s && return 0\n", shellQuote(marker))
+	}
+	if len(activation.Remotes) > 0 {
+		out.WriteString("    _dotwaifu_load_remotes\n")
+		out.WriteString("    case \"$_dotwaifu_remotes\" in\n")
+		for _, remote := range activation.Remotes {
+			fmt.Fprintf(out, "        *\" \"%s\" \"*) return 0 ;;\n", shellPattern(remote))
+		}
+		out.WriteString("    esac\n")
+	}
+	out.WriteString("    return 1\n}\n\n")
+
+	fmt.Fprintf(out, "_dotwaifu_load_%s() {\n", ident)
+	fmt.Fprintf(out, "    case \" $DOTWAIFU_ACTIVE_PROJECTS \" in *\" \"%s\" \"*) return 0 ;; esac\n", name)
+	for i, variable := range symbols.Vars {
+		fmt.Fprintf(out, "    _dotwaifu_save_var _dotwaifu_%s_v%d %s\n", ident, i, variable)
+	}
+	for i, alias := range symbols.Aliases {
+		fmt.Fprintf(out, "    _dotwaifu_save_alias _dotwaifu_%s_a%d %s\n", ident, i, shellQuote(alias))
+	}
+	fmt.Fprintf(out, "    _dotwaifu_%s_path_before=$PATH\n", ident)
+	sourceList(out, "    ", modules)
+	out.WriteString("    _dotwaifu_save_status\n")
+	fmt.Fprintf(out, "    _dotwaifu_%s_path_after=$PATH\n", ident)
+	fmt.Fprintf(out, "    DOTWAIFU_ACTIVE_PROJECTS=\"${DOTWAIFU_ACTIVE_PROJECTS:+$DOTWAIFU_ACTIVE_PROJECTS }\"%s\n", name)
+	out.WriteString("}\n\n")
+
+	fmt.Fprintf(out, "_dotwaifu_unload_%s() {\n", ident)
+	fmt.Fprintf(out, "    case \" $DOTWAIFU_ACTIVE_PROJECTS \" in *\" \"%s\" \"*) ;; *) return 0 ;; esac\n", name)
+	for _, function := range symbols.Functions {
+		fmt.Fprintf(out, "    unset -f %s 2>/dev/null\n", shellQuote(function))
+	}
+	for i, alias := range symbols.Aliases {
+		fmt.Fprintf(out, "    _dotwaifu_restore_alias _dotwaifu_%s_a%d %s\n", ident, i, shellQuote(alias))
+	}
+	for i, variable := range symbols.Vars {
+		fmt.Fprintf(out, "    _dotwaifu_restore_var _dotwaifu_%s_v%d %s\n", ident, i, variable)
+	}
+	fmt.Fprintf(out, "    _dotwaifu_path_remove_added \"$_dotwaifu_%s_path_before\" \"$_dotwaifu_%s_path_after\"\n", ident, ident)
+	fmt.Fprintf(out, "    _dotwaifu_forget_project %s\n", name)
+	out.WriteString("}\n")
+}
+
+const activationHook = `
+_dotwaifu_activate() {
+    [ "$PWD" = "$_dotwaifu_activated_pwd" ] && return 0
+    _dotwaifu_activated_pwd=$PWD
+%s}
+
+if [ -n "$ZSH_VERSION" ]; then
+    autoload -Uz add-zsh-hook
+    add-zsh-hook chpwd _dotwaifu_activate
+else
+    case ";${PROMPT_COMMAND:-};" in
+        *";_dotwaifu_activate;"*) ;;
+        *) PROMPT_COMMAND="_dotwaifu_activate${PROMPT_COMMAND:+;$PROMPT_COMMAND}" ;;
+    esac
+fi
+_dotwaifu_activate
+`
+
+// LoaderScript compiles the modules into a POSIX loader that sources them in
+// the order of SortModules. With a nil project list, core modules and enabled
+// projects without activation rules are loaded right away, and projects with
+// activation rules are loaded and unloaded by a chpwd (zsh) or PROMPT_COMMAND
+// (bash) hook as the working directory changes. Otherwise core and exactly
+// the listed projects are loaded, and DOTWAIFU_ACTIVE_PROJECTS names them.
+func LoaderScript(only []string) (string, error) {
+	var out strings.Builder
+	out.WriteString("# Generated by dotwaifu from ~/.config/dotwaifu/shell/shared - DO NOT EDIT MANUALLY\n")
+	out.WriteString("# 'dotwaifu reload' regenerates it\n\n")
+
+	if err := compileLoader(&out, only, ""); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// compileLoader writes the body of LoaderScript. With inlineShell set, the
+// modules that are loaded right away are copied into the script instead of
+// sourced, see inlineModules.
+func compileLoader(out *strings.Builder, only []string, inlineShell string) error {
+	out.WriteString(safeModeCheck + "\n")
+	if only == nil && inlineShell == "" {
+		// The bundle checks for itself, and a project selection is only used
+		// once
+		watched, err := moduleWatchList()
+		if err != nil {
+			return err
+		}
+		out.WriteString(freshnessCheck("loader", watched, "dotwaifu reload --quiet", "changed since the loader was generated, run 'dotwaifu reload'") + "\n")
+	}
+	out.WriteString(cacheHelper + "\n")
+	out.WriteString(statusHelpers + "\n")
+
+	if only != nil {
+		for _, project := range only {
+			if !ProjectExists(project) {
+				return fmt.Errorf("project '%s' does not exist", project)
+			}
+		}
+
+		modules, err := ListModules()
+		if err != nil {
+			return err
+		}
+		var selected []Module
+		for _, module := range modules {
+			if module.Project == "" || contains(only, module.Project) {
+				selected = append(selected, module)
+			}
+		}
+		sorted, err := SortModules(selected)
+		if err != nil {
+			return err
+		}
+
+		out.WriteString("# Load core and the selected projects\n")
+		if err := writeModules(out, sorted, ""); err != nil {
+			return err
+		}
+		out.WriteString("_dotwaifu_save_status\n")
+		fmt.Fprintf(out, "\nexport DOTWAIFU_ACTIVE_PROJECTS=%s\n", shellQuote(strings.Join(only, " ")))
+		return nil
+	}
+
+	// A 'dotwaifu shell' further up may have exported its own list
+	out.WriteString("unset DOTWAIFU_ACTIVE_PROJECTS\n\n")
+
+	projects, err := ListProjects()
+	if err != nil {
+		return err
+	}
+
+	var managed []string
+	activations := make(map[string]Activation)
+	for _, project := range projects {
+		manifest, err := LoadProjectManifest(project)
+		if err != nil {
+			return fmt.Errorf("%s: %w", GetProjectManifestPath(project), err)
+		}
+		if !manifest.Disabled && manifest.Activation.IsSet() {
+			managed = append(managed, project)
+			activations[project] = manifest.Activation
+		}
+	}
+
+	order, err := LoadOrder()
+	if err != nil {
+		return err
+	}
+
+	var always []Module
+	projectModules := make(map[string][]Module)
+	for _, module := range order {
+		if contains(managed, module.Project) {
+			projectModules[module.Project] = append(projectModules[module.Project], module)
+			continue
+		}
+
+		// Projects with activation rules load later, if at all
+		after, err := module.After()
+		if err != nil {
+			return err
+		}
+		for _, target := range after {
+			for _, project := range managed {
+				if strings.HasPrefix(target, "projects/"+project+"/") {
+					return fmt.Errorf("%s: cannot load after %s, which is only loaded in matching directories", module.RelPath(), target)
+				}
+			}
+		}
+		always = append(always, module)
+	}
+
+	out.WriteString("# Load core and project configurations; disabled projects are skipped and\n")
+	out.WriteString("# projects with activation rules are loaded by _dotwaifu_activate instead\n")
+	if err := writeModules(out, always, inlineShell); err != nil {
+		return err
+	}
+	out.WriteString("_dotwaifu_save_status\n")
+
+	if len(managed) > 0 {
+		out.WriteString("\n" + activationHelpers)
+
+		var hook strings.Builder
+		for _, project := range managed {
+			symbols, err := collectSymbols(projectModules[project])
+			if err != nil {
+				return err
+			}
+			writeActivation(out, project, activations[project], projectModules[project], symbols)
+
+			ident := projectIdent(project)
+			fmt.Fprintf(&hook, "    if _dotwaifu_match_%s; then\n        _dotwaifu_load_%s\n    else\n        _dotwaifu_unload_%s\n    fi\n", ident, ident, ident)
+		}
+
+		fmt.Fprintf(out, activationHook, hook.String())
+	}
+
+	return nil
+}
+
+// GenerateLoader writes the loader that POSIX shells source from their RC
+// file, see LoaderScript.
+func GenerateLoader() error {
+	script, err := LoaderScript(nil)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(GetLoaderPath()), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(GetLoaderPath(), []byte(script), 0644)
+}
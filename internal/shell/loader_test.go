@@ -0,0 +1,225 @@
+package shell
+
+import (
+	"dotwaifu/internal/config"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runBash runs script in a new bash with DOTWAIFU_CONFIG_ROOT set and returns
+// what it printed on stdout.
+func runBash(t *testing.T, script string) string {
+	t.Helper()
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+
+	cmd := exec.Command("bash", "--norc", "--noprofile", "-c", script)
+	cmd.Env = append(cmd.Environ(), "DOTWAIFU_CONFIG_ROOT="+config.GetConfigDir(), "PATH=/usr/bin:/bin")
+	var out, errOut strings.Builder
+	cmd.Stdout, cmd.Stderr = &out, &errOut
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("bash: %v\n%s", err, errOut.String())
+	}
+	return out.String()
+}
+
+// runLoader generates the loader and sources it in a new bash followed by
+// script.
+func runLoader(t *testing.T, script string) string {
+	t.Helper()
+	if err := GenerateLoader(); err != nil {
+		t.Fatalf("GenerateLoader: %v", err)
+	}
+	return runBash(t, `source "$DOTWAIFU_CONFIG_ROOT/state/loader.sh"`+"\n"+script)
+}
+
+func saveManifest(t *testing.T, project string, activation Activation) {
+	t.Helper()
+	if err := SaveProjectManifest(project, &ProjectManifest{Activation: activation}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDirPattern(t *testing.T) {
+	tests := map[string]string{
+		"~":           `"$HOME"/*`,
+		"~/code/app/": `"$HOME"'/code/app/'*`,
+		"/srv/*/web":  `'/srv/'*'/web/'*`,
+		"flutter":     `*/'flutter/'*`,
+		"it's":        `*/'it'\''s/'*`,
+	}
+
+	for glob, want := range tests {
+		if got := dirPattern(glob); got != want {
+			t.Errorf("dirPattern(%q) = %s, want %s", glob, got, want)
+		}
+	}
+}
+
+func TestActivationLoadsAndUnloadsProject(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh":                 "export EDITOR=vim\n",
+		"core/aliases.sh":             "alias g=git\n",
+		"projects/flutter/env.sh":     "export EDITOR=code\nexport FLUTTER_ROOT=/opt/flutter\n",
+		"projects/flutter/paths.sh":   "export PATH=\"/opt/flutter/bin:$PATH\"\n",
+		"projects/flutter/aliases.sh": "alias g='git flutter'\nfl_run() { flutter run; }\n",
+		"projects/rust/env.sh":        "export CARGO_HOME=/opt/cargo\n",
+	})
+	saveManifest(t, "flutter", Activation{Dirs: []string{"~/code/app"}})
+
+	show := `echo "[$DOTWAIFU_ACTIVE_PROJECTS] $EDITOR ${FLUTTER_ROOT-unset} ${CARGO_HOME-unset} $(alias g) $(type -t fl_run) $PATH"` + "\n"
+	got := runLoader(t, `mkdir -p "$HOME/code/app/lib"
+`+show+`cd "$HOME/code/app/lib"; _dotwaifu_activate
+`+show+`cd "$HOME/code/app/lib"; _dotwaifu_activate
+`+show+`cd "$HOME"; _dotwaifu_activate
+`+show)
+
+	want := []string{
+		"[] vim unset /opt/cargo alias g='git'  /usr/bin:/bin",
+		"[flutter] code /opt/flutter /opt/cargo alias g='git flutter' function /opt/flutter/bin:/usr/bin:/bin",
+		"[flutter] code /opt/flutter /opt/cargo alias g='git flutter' function /opt/flutter/bin:/usr/bin:/bin",
+		"[] vim unset /opt/cargo alias g='git'  /usr/bin:/bin",
+	}
+	if got != strings.Join(want, "\n")+"\n" {
+		t.Errorf("got\n%s\nwant\n%s", got, strings.Join(want, "\n"))
+	}
+}
+
+func TestActivationByMarker(t *testing.T) {
+	setupModules(t, map[string]string{
+		"projects/node/env.sh": "export NODE_ENV=development\n",
+	})
+	saveManifest(t, "node", Activation{Markers: []string{"package.json"}})
+
+	got := runLoader(t, `mkdir -p "$HOME/web/src" "$HOME/other"
+touch "$HOME/web/package.json"
+cd "$HOME/web/src"; _dotwaifu_activate
+echo "${NODE_ENV-unset}"
+cd "$HOME/other"; _dotwaifu_activate
+echo "${NODE_ENV-unset}"
+`)
+	if got != "development\nunset\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestLoaderSkipsConflictedModules(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh":     "export EDITOR=vim\n",
+		"core/aliases.sh": "export CONFLICTED=1\n",
+	})
+	marker := filepath.Join(config.GetStateDir(), "conflicts", "shell", "shared", "core", "aliases.sh")
+	if err := os.MkdirAll(filepath.Dir(marker), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := runLoader(t, `echo "$EDITOR ${CONFLICTED-unset}"`); got != "vim unset\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestLoaderScriptWithProjects(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh":             "export EDITOR=vim\n",
+		"projects/flutter/env.sh": "export FLUTTER_ROOT=/opt/flutter\n",
+		"projects/node/env.sh":    "export NODE_ENV=development\n",
+		"projects/rust/env.sh":    "export CARGO_HOME=/opt/cargo\n",
+	})
+	saveManifest(t, "flutter", Activation{Dirs: []string{"~/code/app"}})
+
+	// The loader keeps its scratch files in the state directory, which
+	// exists once dotwaifu has generated anything
+	if err := os.MkdirAll(config.GetStateDir(), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// Activation rules are ignored and unlisted projects are left out
+	script, err := LoaderScript([]string{"flutter", "node"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := runBash(t, script+`echo "$EDITOR ${FLUTTER_ROOT-unset} ${NODE_ENV-unset} ${CARGO_HOME-unset} [$DOTWAIFU_ACTIVE_PROJECTS]"`)
+	if got != "vim /opt/flutter development unset [flutter node]\n" {
+		t.Errorf("got %q", got)
+	}
+
+	if _, err := LoaderScript([]string{"go"}); err == nil {
+		t.Error("LoaderScript accepted a project that does not exist")
+	}
+
+	// The regular loader drops the list a 'dotwaifu shell' further up exported
+	t.Setenv("DOTWAIFU_ACTIVE_PROJECTS", "flutter node")
+	if got := runLoader(t, `echo "${DOTWAIFU_ACTIVE_PROJECTS-unset} ${NODE_ENV-unset}"`); got != "unset development\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestLoaderSkipsDisabledProjects(t *testing.T) {
+	setupModules(t, map[string]string{
+		"projects/flutter/env.sh": "export FLUTTER_ROOT=/opt/flutter\n",
+		"projects/node/env.sh":    "export NODE_ENV=development\n",
+	})
+	if err := SetProjectDisabled("flutter", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := runLoader(t, `echo "${FLUTTER_ROOT-unset} ${NODE_ENV-unset}"`); got != "unset development\n" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestLoaderSourcesModulesInTypeOrder(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/aliases.sh":     "ORDER=\"$ORDER aliases\"\n",
+		"core/paths.sh":       "ORDER=\"$ORDER paths\"\n",
+		"core/completions.sh": "ORDER=\"$ORDER completions\"\n",
+		"core/extra.sh":       "ORDER=\"$ORDER extra\"\n",
+	})
+	cfg := &config.Config{Types: []config.ConfigType{{Name: "completions", Order: 5}, {Name: "paths", Order: 1}}}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := runLoader(t, `echo $ORDER`); got != "paths completions aliases extra\n" {
+		t.Errorf("modules were sourced in the order %q", got)
+	}
+}
+
+func TestLoaderRegeneratesWhenStale(t *testing.T) {
+	setupModules(t, map[string]string{"core/env.sh": "export EDITOR=vim\n"})
+	if err := GenerateLoader(); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(filepath.Join(GetCoreDir(), "env.sh"), later, later); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without dotwaifu on PATH the loader can only warn
+	got := runBash(t, `source "$DOTWAIFU_CONFIG_ROOT/state/loader.sh" 2>&1; echo "$EDITOR"`)
+	if got != "dotwaifu: shell/shared/core/env.sh changed since the loader was generated, run 'dotwaifu reload'\nvim\n" {
+		t.Errorf("got %q", got)
+	}
+
+	home, _ := os.UserHomeDir()
+	bin := filepath.Join(home, "bin")
+	if err := os.Mkdir(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	fake := "#!/bin/sh\n[ \"$*\" = \"reload --quiet\" ] && echo 'export REGENERATED=1' > \"$DOTWAIFU_CONFIG_ROOT/state/loader.sh\"\n"
+	if err := os.WriteFile(filepath.Join(bin, "dotwaifu"), []byte(fake), 0755); err != nil {
+		t.Fatal(err)
+	}
+	got = runBash(t, `PATH="$HOME/bin:$PATH"; source "$DOTWAIFU_CONFIG_ROOT/state/loader.sh"; echo "${REGENERATED-unset} ${EDITOR-unset}"`)
+	if got != "1 unset\n" {
+		t.Errorf("after regenerating got %q", got)
+	}
+}
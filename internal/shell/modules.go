@@ -0,0 +1,140 @@
+package shell
+
+import (
+	"dotwaifu/internal/config"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Module is a single .sh file under shell/shared, either in core or in a
+// project directory.
+type Module struct {
+	Project string
+	Type    string
+	Path    string
+}
+
+// RelPath returns the module path relative to shell/shared, e.g.
+// "core/aliases.sh" or "projects/flutter/paths.sh".
+func (m Module) RelPath() string {
+	if m.Project == "" {
+		return "core/" + m.Type + ".sh"
+	}
+	return "projects/" + m.Project + "/" + m.Type + ".sh"
+}
+
+// HasConflict reports whether the module has an unresolved sync conflict and
+// must not be loaded.
+func (m Module) HasConflict() bool {
+	marker := filepath.Join(config.GetStateDir(), "conflicts", "shell", "shared", filepath.FromSlash(m.RelPath()))
+	_, err := os.Stat(marker)
+	return err == nil
+}
+
+// IsDisabled reports whether the module belongs to a disabled project.
+func (m Module) IsDisabled() bool {
+	return m.Project != "" && IsProjectDisabled(m.Project)
+}
+
+func GetSharedDir() string {
+	return filepath.Join(config.GetConfigDir(), "shell", "shared")
+}
+
+func GetCoreDir() string {
+	return filepath.Join(GetSharedDir(), "core")
+}
+
+func GetProjectsDir() string {
+	return filepath.Join(GetSharedDir(), "projects")
+}
+
+// ListProjects returns the project directory names in load order.
+func ListProjects() ([]string, error) {
+	entries, err := os.ReadDir(GetProjectsDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var projects []string
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			projects = append(projects, entry.Name())
+		}
+	}
+	sort.Strings(projects)
+	return projects, nil
+}
+
+// listModulesIn returns the modules in dir, in the load order of types.
+func listModulesIn(dir, project string, types []config.ConfigType) ([]Module, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.sh"))
+	if err != nil {
+		return nil, err
+	}
+	sortByLoadOrder(matches, types)
+
+	modules := make([]Module, 0, len(matches))
+	for _, match := range matches {
+		modules = append(modules, Module{
+			Project: project,
+			Type:    strings.TrimSuffix(filepath.Base(match), ".sh"),
+			Path:    match,
+		})
+	}
+	return modules, nil
+}
+
+// sortByLoadOrder sorts module paths by the load order of their config type.
+// Modules of unknown types come last, by name.
+func sortByLoadOrder(paths []string, types []config.ConfigType) {
+	rank := make(map[string]int, len(types))
+	for i, t := range types {
+		rank[t.Name] = i
+	}
+	rankOf := func(path string) int {
+		if r, ok := rank[strings.TrimSuffix(filepath.Base(path), ".sh")]; ok {
+			return r
+		}
+		return len(types)
+	}
+
+	sort.Strings(paths)
+	sort.SliceStable(paths, func(i, j int) bool {
+		return rankOf(paths[i]) < rankOf(paths[j])
+	})
+}
+
+// ListModules returns every core module followed by every project module, each
+// directory in the load order of the config types. LoadOrder also applies the
+// after= directives of the modules.
+func ListModules() ([]Module, error) {
+	types, err := config.LoadTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	modules, err := listModulesIn(GetCoreDir(), "", types)
+	if err != nil {
+		return nil, err
+	}
+
+	projects, err := ListProjects()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, project := range projects {
+		projectModules, err := listModulesIn(filepath.Join(GetProjectsDir(), project), project, types)
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, projectModules...)
+	}
+
+	return modules, nil
+}
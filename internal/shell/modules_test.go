@@ -0,0 +1,99 @@
+package shell
+
+import (
+	"dotwaifu/internal/config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// setupModules creates a config directory in a fresh HOME holding the given
+// modules, keyed by path relative to shell/shared.
+func setupModules(t *testing.T, modules map[string]string) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+
+	for _, dir := range []string{GetCoreDir(), GetProjectsDir()} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for path, content := range modules {
+		fullPath := filepath.Join(GetSharedDir(), filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestListModules(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/paths.sh":               "",
+		"core/aliases.sh":             "",
+		"core/notes.txt":              "",
+		"projects/rust/env.sh":        "",
+		"projects/flutter/paths.sh":   "",
+		"projects/flutter/aliases.sh": "",
+		"projects/.hidden/env.sh":     "",
+	})
+
+	modules, err := ListModules()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for _, module := range modules {
+		paths = append(paths, module.RelPath())
+	}
+	want := []string{
+		"core/aliases.sh",
+		"core/paths.sh",
+		"projects/flutter/aliases.sh",
+		"projects/flutter/paths.sh",
+		"projects/rust/env.sh",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("ListModules = %v, want %v", paths, want)
+	}
+}
+
+func TestListModulesInTypeOrder(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/aliases.sh":          "",
+		"core/env.sh":              "",
+		"core/completions.sh":      "",
+		"core/extra.sh":            "",
+		"projects/node/scripts.sh": "",
+		"projects/node/paths.sh":   "",
+	})
+	cfg := &config.Config{Types: []config.ConfigType{{Name: "completions", Order: 5}}}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	modules, err := ListModules()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for _, module := range modules {
+		paths = append(paths, module.RelPath())
+	}
+	want := []string{
+		"core/completions.sh",
+		"core/aliases.sh",
+		"core/env.sh",
+		"core/extra.sh",
+		"projects/node/paths.sh",
+		"projects/node/scripts.sh",
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("ListModules = %v, want %v", paths, want)
+	}
+}
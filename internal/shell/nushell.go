@@ -0,0 +1,195 @@
+package shell
+
+import (
+	"dotwaifu/internal/config"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+var nuInterpolationEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "(", `\(`, ")", `\)`)
+
+// GetNushellConfigDir mirrors where nushell looks for config.nu.
+func GetNushellConfigDir() string {
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		return filepath.Join(xdg, "nushell")
+	}
+
+	home, _ := os.UserHomeDir()
+	if runtime.GOOS == "darwin" {
+		return filepath.Join(home, "Library", "Application Support", "nushell")
+	}
+	return filepath.Join(home, ".config", "nushell")
+}
+
+// GetNushellModulesDir is where the generated env.nu and config.nu fragments
+// are written.
+func GetNushellModulesDir() string {
+	return filepath.Join(config.GetStateDir(), "nu")
+}
+
+func nuEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// nuString renders a POSIX shell value as a nushell string, using string
+// interpolation for variable references.
+func nuString(value string, quote byte) (string, bool) {
+	parts, ok := ValueParts(value, quote)
+	if !ok {
+		return "", false
+	}
+
+	interpolated := false
+	for _, part := range parts {
+		interpolated = interpolated || part.Variable != ""
+	}
+
+	var out strings.Builder
+	for _, part := range parts {
+		switch {
+		case part.Variable != "":
+			out.WriteString("($env." + part.Variable + ")")
+		case interpolated:
+			// Parentheses are only special inside interpolated strings
+			out.WriteString(nuInterpolationEscaper.Replace(part.Literal))
+		default:
+			out.WriteString(nuEscape(part.Literal))
+		}
+	}
+
+	if interpolated {
+		return `$"` + out.String() + `"`, true
+	}
+	return `"` + out.String() + `"`, true
+}
+
+// nuAliasBody translates a simple alias body (a command and plain arguments)
+// into a nushell alias that runs the external command.
+func nuAliasBody(value string) (string, bool) {
+	words, ok := SimpleCommandWords(value)
+	if !ok {
+		return "", false
+	}
+
+	if words[0] != "cd" {
+		words[0] = "^" + words[0]
+	}
+	return strings.Join(words, " "), true
+}
+
+// TranslateToNushell splits a module into an env.nu fragment (variables and
+// PATH changes) and a config.nu fragment (aliases). Lines that cannot be
+// translated are returned for reporting.
+func TranslateToNushell(content string) (env string, aliases string, untranslated []Statement) {
+	var envOut, aliasOut strings.Builder
+
+	for _, statement := range ParseModule(content) {
+		switch statement.Kind {
+		case StatementBlank, StatementComment:
+			continue
+		case StatementExport:
+			value, ok := nuString(statement.Value, statement.Quote)
+			if ok && statement.Exported {
+				envOut.WriteString(fmt.Sprintf("$env.%s = %s\n", statement.Name, value))
+				continue
+			}
+		case StatementPath:
+			var entries []string
+			ok := true
+			for _, entry := range statement.Entries {
+				value, entryOK := nuString(entry, statement.Quote)
+				ok = ok && entryOK
+				entries = append(entries, value)
+			}
+			if ok {
+				verb := "prepend"
+				if statement.Append {
+					verb = "append"
+				}
+				envOut.WriteString(fmt.Sprintf("$env.PATH = ($env.PATH | split row (char esep) | %s [%s])\n", verb, strings.Join(entries, ", ")))
+				continue
+			}
+		case StatementAlias:
+			if body, ok := nuAliasBody(statement.Value); ok {
+				aliasOut.WriteString(fmt.Sprintf("alias %s = %s\n", statement.Name, body))
+				continue
+			}
+		}
+
+		untranslated = append(untranslated, statement)
+	}
+
+	return envOut.String(), aliasOut.String(), untranslated
+}
+
+// GenerateNushellModules writes the env.nu and config.nu fragments that the
+// nushell integration sources, skipping modules with unresolved conflicts.
+// It returns the lines that could not be translated, keyed by module path
+// relative to shell/shared.
+func GenerateNushellModules() (map[string][]Statement, error) {
+	modules, err := LoadOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	header := "# Generated by dotwaifu from ~/.config/dotwaifu/shell/shared - DO NOT EDIT MANUALLY\n"
+	envContent, configContent := header, header
+	untranslated := make(map[string][]Statement)
+
+	for _, module := range modules {
+		if module.HasConflict() {
+			continue
+		}
+
+		content, err := os.ReadFile(module.Path)
+		if err != nil {
+			return nil, err
+		}
+
+		env, aliases, skipped := TranslateToNushell(string(content))
+		if len(skipped) > 0 {
+			untranslated[module.RelPath()] = skipped
+		}
+
+		if env != "" {
+			envContent += fmt.Sprintf("\n# %s\n%s", module.RelPath(), env)
+		}
+		if aliases != "" {
+			configContent += fmt.Sprintf("\n# %s\n%s", module.RelPath(), aliases)
+		}
+	}
+
+	nuDir := GetNushellModulesDir()
+	if err := os.MkdirAll(nuDir, 0755); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(filepath.Join(nuDir, "env.nu"), []byte(envContent), 0644); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(filepath.Join(nuDir, "config.nu"), []byte(configContent), 0644); err != nil {
+		return nil, err
+	}
+
+	return untranslated, nil
+}
+
+func translateNushellModule(content string) (string, []Statement) {
+	env, aliases, untranslated := TranslateToNushell(content)
+	return env + aliases, untranslated
+}
+
+func nushellLoadingLogic() string {
+	// nushell resolves 'source' at parse time, so the paths must be literal.
+	// For the same reason the fragments cannot be left out at startup, and
+	// DOTWAIFU_SAFE and DOTWAIFU_SKIP have no effect in nushell.
+	nuDir := GetNushellModulesDir()
+	return fmt.Sprintf(`# Nushell cannot read the .sh modules directly; 'dotwaifu reload' translates
+# them into these fragments.
+source "%s"
+source "%s"`, nuEscape(filepath.Join(nuDir, "env.nu")), nuEscape(filepath.Join(nuDir, "config.nu")))
+}
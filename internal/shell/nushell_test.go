@@ -0,0 +1,120 @@
+package shell
+
+import (
+	"dotwaifu/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTranslateToNushell(t *testing.T) {
+	tests := []struct {
+		name         string
+		in           string
+		env          string
+		aliases      string
+		untranslated int
+	}{
+		{
+			name: "comments and blank lines are dropped",
+			in:   "# env\n\n",
+		},
+		{
+			name: "exported variables",
+			in:   "export EDITOR=\"nvim\"\nexport GOPATH=$HOME/go\nexport CONF=\"${XDG_CONFIG_HOME}/app\"\n",
+			env:  "$env.EDITOR = \"nvim\"\n$env.GOPATH = $\"($env.HOME)/go\"\n$env.CONF = $\"($env.XDG_CONFIG_HOME)/app\"\n",
+		},
+		{
+			name: "escaping",
+			in:   "export MSG='say \"hi\" (now)'\nexport DIR=\"$HOME/(x)\"\n",
+			env:  "$env.MSG = \"say \\\"hi\\\" (now)\"\n$env.DIR = $\"($env.HOME)/\\(x\\)\"\n",
+		},
+		{
+			name: "bare tilde",
+			in:   "export NOTES=~/notes\n",
+			env:  "$env.NOTES = $\"($env.HOME)/notes\"\n",
+		},
+		{
+			name: "prepended and appended PATH",
+			in:   "export PATH=\"$HOME/bin:/opt/bin:$PATH\"\nexport PATH=$PATH:/usr/local/go/bin\n",
+			env: "$env.PATH = ($env.PATH | split row (char esep) | prepend [$\"($env.HOME)/bin\", \"/opt/bin\"])\n" +
+				"$env.PATH = ($env.PATH | split row (char esep) | append [\"/usr/local/go/bin\"])\n",
+		},
+		{
+			name:    "aliases run the external command",
+			in:      "alias g=git\nalias ll='ls -la'\nalias ..='cd ..'\n",
+			aliases: "alias g = ^git\nalias ll = ^ls -la\nalias .. = cd ..\n",
+		},
+		{
+			name:         "plain variables are not exported",
+			in:           "HISTSIZE=1000\n",
+			untranslated: 1,
+		},
+		{
+			name:         "aliases with pipes",
+			in:           "alias lsl='ls | less'\n",
+			untranslated: 1,
+		},
+		{
+			name:         "command substitution",
+			in:           "export TODAY=\"$(date +%F)\"\nexport PATH=\"$(go env GOPATH)/bin:$PATH\"\n",
+			untranslated: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, aliases, untranslated := TranslateToNushell(tt.in)
+			if env != tt.env {
+				t.Errorf("env =\n%s\nwant\n%s", env, tt.env)
+			}
+			if aliases != tt.aliases {
+				t.Errorf("aliases =\n%s\nwant\n%s", aliases, tt.aliases)
+			}
+			if len(untranslated) != tt.untranslated {
+				t.Errorf("%d untranslated lines, want %d", len(untranslated), tt.untranslated)
+			}
+		})
+	}
+}
+
+func TestGenerateNushellModules(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh":          "export EDITOR=\"nvim\"\n",
+		"core/aliases.sh":      "alias g='git'\n",
+		"projects/rust/env.sh": "export CARGO_HOME=\"$HOME/.cargo\"\n",
+	})
+
+	// A module with an unresolved sync conflict is left out
+	marker := filepath.Join(config.GetStateDir(), "conflicts", "shell", "shared", "projects", "rust", "env.sh")
+	if err := os.MkdirAll(filepath.Dir(marker), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := GenerateNushellModules(); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := os.ReadFile(filepath.Join(GetNushellModulesDir(), "env.nu"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(env), "# core/env.sh\n$env.EDITOR = \"nvim\"\n") {
+		t.Errorf("env.nu = %q", env)
+	}
+	if strings.Contains(string(env), "CARGO_HOME") {
+		t.Errorf("env.nu contains a conflicted module: %q", env)
+	}
+
+	aliases, err := os.ReadFile(filepath.Join(GetNushellModulesDir(), "config.nu"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(aliases), "# core/aliases.sh\n") {
+		t.Errorf("config.nu = %q", aliases)
+	}
+}
@@ -0,0 +1,181 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var directivePattern = regexp.MustCompile(`^\s*#\s*dotwaifu:\s*(.*)$`)
+
+// ParseDirectives returns the "# dotwaifu: key=value" directives of a module.
+// Values are comma-separated lists, and repeated keys accumulate.
+func ParseDirectives(content string) map[string][]string {
+	directives := make(map[string][]string)
+
+	for _, line := range strings.Split(content, "\n") {
+		match := directivePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		for _, field := range strings.Fields(match[1]) {
+			key, value, ok := strings.Cut(field, "=")
+			if !ok {
+				continue
+			}
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					directives[key] = append(directives[key], item)
+				}
+			}
+		}
+	}
+
+	return directives
+}
+
+// resolveAfter turns an after= reference of module into a path relative to
+// shell/shared. A bare type such as "env" names the module of that type next
+// to module; "core/env" names a core module and "flutter/env" (or
+// "projects/flutter/env") a project module.
+func resolveAfter(module Module, ref string) string {
+	ref = strings.TrimSuffix(ref, ".sh")
+
+	switch parts := strings.Split(ref, "/"); {
+	case len(parts) == 1:
+		return Module{Project: module.Project, Type: ref}.RelPath()
+	case len(parts) == 2 && parts[0] == "core":
+		return Module{Type: parts[1]}.RelPath()
+	case len(parts) == 2:
+		return Module{Project: parts[0], Type: parts[1]}.RelPath()
+	case len(parts) == 3 && parts[0] == "projects":
+		return Module{Project: parts[1], Type: parts[2]}.RelPath()
+	}
+	return ref + ".sh"
+}
+
+// After returns the modules, relative to shell/shared, that module has to be
+// loaded after according to its after= directives.
+func (m Module) After() ([]string, error) {
+	content, err := os.ReadFile(m.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var after []string
+	for _, ref := range ParseDirectives(string(content))["after"] {
+		target := resolveAfter(m, ref)
+		if target == m.RelPath() {
+			return nil, fmt.Errorf("%s: after=%s names the module itself", m.RelPath(), ref)
+		}
+		if _, err := os.Stat(filepath.Join(GetSharedDir(), filepath.FromSlash(target))); err != nil {
+			return nil, fmt.Errorf("%s: after=%s: %s does not exist", m.RelPath(), ref, target)
+		}
+		after = append(after, target)
+	}
+	return after, nil
+}
+
+// SortModules orders modules so that every module comes after the modules
+// its after= directives name, keeping the given order wherever the
+// directives allow. Directives naming modules that are not in the list, such
+// as those of disabled projects, are ignored. It fails on cycles.
+func SortModules(modules []Module) ([]Module, error) {
+	index := make(map[string]int, len(modules))
+	for i, module := range modules {
+		index[module.RelPath()] = i
+	}
+
+	deps := make([][]int, len(modules))
+	for i, module := range modules {
+		after, err := module.After()
+		if err != nil {
+			return nil, err
+		}
+		for _, target := range after {
+			if j, ok := index[target]; ok {
+				deps[i] = append(deps[i], j)
+			}
+		}
+	}
+
+	placed := make([]bool, len(modules))
+	sorted := make([]Module, 0, len(modules))
+	for len(sorted) < len(modules) {
+		next := -1
+		for i := range modules {
+			if !placed[i] && allPlaced(deps[i], placed) {
+				next = i
+				break
+			}
+		}
+		if next == -1 {
+			return nil, cycleError(modules, deps, placed)
+		}
+
+		placed[next] = true
+		sorted = append(sorted, modules[next])
+	}
+
+	return sorted, nil
+}
+
+func allPlaced(deps []int, placed []bool) bool {
+	for _, dep := range deps {
+		if !placed[dep] {
+			return false
+		}
+	}
+	return true
+}
+
+// cycleError describes a dependency cycle among the modules that could not
+// be placed. Every such module waits for another unplaced one, so following
+// those edges must come back around.
+func cycleError(modules []Module, deps [][]int, placed []bool) error {
+	current := 0
+	for placed[current] {
+		current++
+	}
+
+	position := make(map[int]int)
+	var path []int
+	for {
+		if start, seen := position[current]; seen {
+			var names []string
+			for _, i := range append(path[start:], current) {
+				names = append(names, modules[i].RelPath())
+			}
+			return fmt.Errorf("module load order has a cycle: %s", strings.Join(names, " after "))
+		}
+
+		position[current] = len(path)
+		path = append(path, current)
+		for _, dep := range deps[current] {
+			if !placed[dep] {
+				current = dep
+				break
+			}
+		}
+	}
+}
+
+// LoadOrder returns the modules of core and of enabled projects in the order
+// they are loaded, see SortModules.
+func LoadOrder() ([]Module, error) {
+	modules, err := ListModules()
+	if err != nil {
+		return nil, err
+	}
+
+	var enabled []Module
+	for _, module := range modules {
+		if !module.IsDisabled() {
+			enabled = append(enabled, module)
+		}
+	}
+	return SortModules(enabled)
+}
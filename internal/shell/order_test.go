@@ -0,0 +1,114 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+)
+
+func loadOrderPaths(t *testing.T) []string {
+	t.Helper()
+	modules, err := LoadOrder()
+	if err != nil {
+		t.Fatalf("LoadOrder: %v", err)
+	}
+
+	var paths []string
+	for _, module := range modules {
+		paths = append(paths, module.RelPath())
+	}
+	return paths
+}
+
+func TestParseDirectives(t *testing.T) {
+	directives := ParseDirectives("# dotwaifu: after=env,paths lazy=nvm\nexport A=1\n#dotwaifu: after=core/aliases\n# dotwaifu: broken\n")
+
+	if got := strings.Join(directives["after"], " "); got != "env paths core/aliases" {
+		t.Errorf("after = %q", got)
+	}
+	if got := strings.Join(directives["lazy"], " "); got != "nvm" {
+		t.Errorf("lazy = %q", got)
+	}
+	if len(directives) != 2 {
+		t.Errorf("directives = %v", directives)
+	}
+}
+
+func TestResolveAfter(t *testing.T) {
+	module := Module{Project: "flutter", Type: "paths"}
+	tests := map[string]string{
+		"env":                  "projects/flutter/env.sh",
+		"env.sh":               "projects/flutter/env.sh",
+		"core/env":             "core/env.sh",
+		"node/env":             "projects/node/env.sh",
+		"projects/node/env.sh": "projects/node/env.sh",
+	}
+
+	for ref, want := range tests {
+		if got := resolveAfter(module, ref); got != want {
+			t.Errorf("resolveAfter(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
+
+func TestSortModules(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/aliases.sh":           "# dotwaifu: after=paths\n",
+		"core/env.sh":               "",
+		"core/paths.sh":             "# dotwaifu: after=env\n",
+		"projects/node/env.sh":      "",
+		"projects/node/scripts.sh":  "# dotwaifu: after=core/aliases,env\n",
+		"projects/flutter/paths.sh": "# dotwaifu: after=node/scripts\n",
+	})
+
+	want := "core/env.sh core/paths.sh core/aliases.sh projects/node/env.sh projects/node/scripts.sh projects/flutter/paths.sh"
+	if got := strings.Join(loadOrderPaths(t), " "); got != want {
+		t.Errorf("load order = %s, want %s", got, want)
+	}
+}
+
+func TestSortModulesIgnoresDisabledProjects(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/aliases.sh":      "# dotwaifu: after=work/env\n",
+		"projects/work/env.sh": "",
+	})
+	if err := SetProjectDisabled("work", true); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := strings.Join(loadOrderPaths(t), " "); got != "core/aliases.sh" {
+		t.Errorf("load order = %s", got)
+	}
+}
+
+func TestSortModulesCycle(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/aliases.sh": "",
+		"core/env.sh":     "# dotwaifu: after=scripts\n",
+		"core/paths.sh":   "# dotwaifu: after=env\n",
+		"core/scripts.sh": "# dotwaifu: after=paths,aliases\n",
+	})
+
+	_, err := LoadOrder()
+	if err == nil {
+		t.Fatal("LoadOrder succeeded on a cycle")
+	}
+	want := "module load order has a cycle: core/env.sh after core/scripts.sh after core/paths.sh after core/env.sh"
+	if err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+}
+
+func TestAfterErrors(t *testing.T) {
+	tests := map[string]string{
+		"# dotwaifu: after=env\n":     "names the module itself",
+		"# dotwaifu: after=missing\n": "core/missing.sh does not exist",
+	}
+
+	for content, want := range tests {
+		setupModules(t, map[string]string{"core/env.sh": content})
+		_, err := LoadOrder()
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadOrder with %q: err = %v, want %q", content, err, want)
+		}
+	}
+}
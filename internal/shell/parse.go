@@ -0,0 +1,428 @@
+package shell
+
+import (
+	"regexp"
+	"strings"
+)
+
+type StatementKind int
+
+const (
+	StatementBlank StatementKind = iota
+	StatementComment
+	StatementExport
+	StatementPath
+	StatementAlias
+	StatementOther
+)
+
+// Statement is one line of a module, classified by what it statically does.
+// Only simple one-line assignments, PATH updates and aliases are understood;
+// everything else is StatementOther and has to be run by a POSIX shell.
+type Statement struct {
+	Line int
+	Raw  string
+	Kind StatementKind
+
+	// Name is the variable name (StatementExport) or alias name
+	// (StatementAlias).
+	Name string
+	// Value is the assigned value with its surrounding quotes removed.
+	Value string
+	// Quote is the quote character the value was written with, or 0.
+	Quote byte
+	// Exported is false for plain NAME=value assignments.
+	Exported bool
+
+	// Entries are the directories added by a StatementPath, in order.
+	Entries []string
+	// Append is true when the entries go after the existing $PATH.
+	Append bool
+
+	// Comment is a trailing "# ..." comment, without the leading hash.
+	Comment string
+}
+
+var (
+	identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	aliasNamePattern  = regexp.MustCompile(`^[^\s=$'"` + "`" + `;&|<>()]+$`)
+	simpleWordPattern = regexp.MustCompile(`^[A-Za-z0-9_./:=+~-]+$`)
+)
+
+// ParseModule classifies every line of a module file.
+func ParseModule(content string) []Statement {
+	var statements []Statement
+	for i, line := range strings.Split(strings.TrimSuffix(content, "\n"), "\n") {
+		statement := ParseLine(line)
+		statement.Line = i + 1
+		statements = append(statements, statement)
+	}
+	return statements
+}
+
+// ParseLine classifies a single line of shell code.
+func ParseLine(line string) Statement {
+	statement := Statement{Raw: line, Kind: StatementOther}
+	trimmed := strings.TrimSpace(line)
+
+	switch {
+	case trimmed == "":
+		statement.Kind = StatementBlank
+		return statement
+	case strings.HasPrefix(trimmed, "#"):
+		statement.Kind = StatementComment
+		statement.Comment = strings.TrimSpace(strings.TrimPrefix(trimmed, "#"))
+		return statement
+	}
+
+	if rest, ok := cutKeyword(trimmed, "alias"); ok {
+		parseAlias(&statement, rest)
+		return statement
+	}
+
+	exported := false
+	if rest, ok := cutKeyword(trimmed, "export"); ok {
+		exported = true
+		trimmed = rest
+	}
+
+	parseAssignment(&statement, trimmed, exported)
+	return statement
+}
+
+func cutKeyword(line, keyword string) (string, bool) {
+	if !strings.HasPrefix(line, keyword+" ") && !strings.HasPrefix(line, keyword+"\t") {
+		return "", false
+	}
+	return strings.TrimSpace(line[len(keyword):]), true
+}
+
+func parseAlias(statement *Statement, rest string) {
+	name, value, found := strings.Cut(rest, "=")
+	if !found || !aliasNamePattern.MatchString(name) {
+		return
+	}
+
+	word, quote, comment, ok := parseWord(value)
+	if !ok {
+		return
+	}
+
+	statement.Kind = StatementAlias
+	statement.Name = name
+	statement.Value = word
+	statement.Quote = quote
+	statement.Comment = comment
+}
+
+func parseAssignment(statement *Statement, rest string, exported bool) {
+	name, value, found := strings.Cut(rest, "=")
+	if !found || !identifierPattern.MatchString(name) {
+		return
+	}
+
+	word, quote, comment, ok := parseWord(value)
+	if !ok {
+		return
+	}
+
+	statement.Name = name
+	statement.Value = word
+	statement.Quote = quote
+	statement.Exported = exported
+	statement.Comment = comment
+	statement.Kind = StatementExport
+
+	if name == "PATH" {
+		parsePathValue(statement)
+	}
+}
+
+// parsePathValue turns PATH="a:b:$PATH" or PATH="$PATH:a" into a
+// StatementPath. Anything else touching PATH is left as StatementOther.
+func parsePathValue(statement *Statement) {
+	statement.Kind = StatementOther
+	if statement.Quote == '\'' {
+		return
+	}
+
+	parts := strings.Split(statement.Value, ":")
+	isPath := func(part string) bool { return part == "$PATH" || part == "${PATH}" }
+
+	var entries []string
+	switch {
+	case len(parts) > 1 && isPath(parts[len(parts)-1]):
+		entries = parts[:len(parts)-1]
+	case len(parts) > 1 && isPath(parts[0]):
+		entries = parts[1:]
+		statement.Append = true
+	default:
+		return
+	}
+
+	for _, entry := range entries {
+		if entry == "" || isPath(entry) {
+			return
+		}
+	}
+
+	statement.Kind = StatementPath
+	statement.Entries = entries
+}
+
+// parseWord reads one shell word that may be single-quoted, double-quoted or
+// bare. Only a trailing comment may follow it. Escapes inside double quotes
+// are kept as written.
+func parseWord(s string) (word string, quote byte, comment string, ok bool) {
+	var rest string
+
+	switch {
+	case strings.HasPrefix(s, "'"):
+		end := strings.IndexByte(s[1:], '\'')
+		if end == -1 {
+			return "", 0, "", false
+		}
+		word, quote, rest = s[1:end+1], '\'', s[end+2:]
+	case strings.HasPrefix(s, `"`):
+		end := -1
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+				continue
+			}
+			if s[i] == '"' {
+				end = i
+				break
+			}
+		}
+		if end == -1 {
+			return "", 0, "", false
+		}
+		word, quote, rest = s[1:end], '"', s[end+1:]
+	default:
+		end := strings.IndexAny(s, " \t")
+		if end == -1 {
+			end = len(s)
+		}
+		word, rest = s[:end], s[end:]
+		if strings.ContainsAny(word, `'"\;&|<>()`+"`") {
+			return "", 0, "", false
+		}
+	}
+
+	rest = strings.TrimSpace(rest)
+	switch {
+	case rest == "":
+	case strings.HasPrefix(rest, "#"):
+		comment = strings.TrimSpace(strings.TrimPrefix(rest, "#"))
+	default:
+		return "", 0, "", false
+	}
+
+	return word, quote, comment, true
+}
+
+// HasCommandSubstitution reports whether a double-quoted or bare value runs
+// a command when expanded.
+func (s Statement) HasCommandSubstitution() bool {
+	if s.Quote == '\'' {
+		return false
+	}
+	return strings.Contains(s.Value, "$(") || strings.Contains(s.Value, "`")
+}
+
+// ValuePart is a piece of an assigned value: either literal text or a
+// reference to a variable.
+type ValuePart struct {
+	Literal  string
+	Variable string
+}
+
+// ValueParts splits a value into literal text and $VAR / ${VAR} references so
+// it can be rebuilt in another shell's syntax. It fails for command
+// substitution and for parameter expansions beyond plain references. A
+// leading ~ in a bare value becomes a reference to HOME.
+func ValueParts(value string, quote byte) ([]ValuePart, bool) {
+	if quote == '\'' {
+		return []ValuePart{{Literal: value}}, true
+	}
+
+	var parts []ValuePart
+	var literal strings.Builder
+	flush := func() {
+		if literal.Len() > 0 {
+			parts = append(parts, ValuePart{Literal: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	if quote == 0 && (value == "~" || strings.HasPrefix(value, "~/")) {
+		parts = append(parts, ValuePart{Variable: "HOME"})
+		value = value[1:]
+	}
+
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case c == '\\' && i+1 < len(value):
+			next := value[i+1]
+			if quote == '"' && !strings.ContainsRune("$\"\\`", rune(next)) {
+				literal.WriteByte(c)
+			}
+			literal.WriteByte(next)
+			i++
+		case c == '`':
+			return nil, false
+		case c == '$' && i+1 < len(value) && value[i+1] == '{':
+			end := strings.IndexByte(value[i:], '}')
+			if end == -1 || !identifierPattern.MatchString(value[i+2:i+end]) {
+				return nil, false
+			}
+			flush()
+			parts = append(parts, ValuePart{Variable: value[i+2 : i+end]})
+			i += end
+		case c == '$' && i+1 < len(value) && isIdentifierStart(value[i+1]):
+			end := i + 2
+			for end < len(value) && isIdentifierChar(value[end]) {
+				end++
+			}
+			flush()
+			parts = append(parts, ValuePart{Variable: value[i+1 : end]})
+			i = end - 1
+		case c == '$' && i+1 < len(value) && !strings.ContainsRune(" /:.-", rune(value[i+1])):
+			// $(...), $1, $@ and friends have no static meaning
+			return nil, false
+		default:
+			literal.WriteByte(c)
+		}
+	}
+	flush()
+
+	return parts, true
+}
+
+func isIdentifierStart(c byte) bool {
+	return c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')
+}
+
+func isIdentifierChar(c byte) bool {
+	return isIdentifierStart(c) || (c >= '0' && c <= '9')
+}
+
+// SimpleCommandWords splits an alias body into words when it is a single
+// command with plain arguments: no quoting, expansions, pipes or redirections.
+func SimpleCommandWords(value string) ([]string, bool) {
+	words := strings.Fields(value)
+	if len(words) == 0 {
+		return nil, false
+	}
+
+	for _, word := range words {
+		if !simpleWordPattern.MatchString(word) {
+			return nil, false
+		}
+	}
+	return words, true
+}
+
+// shellTokens splits shell code into words and the operators ; & | ( ) < >
+// and newline, leaving out comments. Quoted text stays part of its word,
+// quotes included, so it never reads as a keyword.
+func shellTokens(content string) []string {
+	var tokens []string
+	var word strings.Builder
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case c == '\\' && i+1 < len(content):
+			if content[i+1] != '\n' {
+				word.WriteString(content[i : i+2])
+			}
+			i++
+		case c == '\'':
+			end := strings.IndexByte(content[i+1:], '\'')
+			if end == -1 {
+				end = len(content) - i - 1
+			}
+			word.WriteString(content[i : i+end+1])
+			i += end + 1
+		case c == '"':
+			start := i
+			for i++; i < len(content) && content[i] != '"'; i++ {
+				if content[i] == '\\' {
+					i++
+				}
+			}
+			word.WriteString(content[start:min(i+1, len(content))])
+		case c == '#' && word.Len() == 0:
+			for i+1 < len(content) && content[i+1] != '\n' {
+				i++
+			}
+		case c == ' ' || c == '\t':
+			flush()
+		case strings.IndexByte("\n;&|()<>", c) >= 0:
+			flush()
+			tokens = append(tokens, string(c))
+		default:
+			word.WriteByte(c)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// hasTopLevelReturn reports whether a return in content can run outside a
+// function, where it ends whatever file sources the module. Only function
+// bodies in braces are recognized; a return the scan cannot place counts as
+// top-level.
+func hasTopLevelReturn(content string) bool {
+	// For each open brace group, whether it is a function body
+	var groups []bool
+	inFunction := func() bool {
+		for _, body := range groups {
+			if body {
+				return true
+			}
+		}
+		return false
+	}
+
+	pendingBody := false
+	tokens := shellTokens(content)
+	for i, token := range tokens {
+		switch token {
+		case "{":
+			groups = append(groups, pendingBody)
+			pendingBody = false
+		case "}":
+			if len(groups) == 0 {
+				return true
+			}
+			groups = groups[:len(groups)-1]
+		case "return":
+			if !inFunction() {
+				return true
+			}
+		case "(", "\n":
+		case ")":
+			// name() { ... }
+			pendingBody = i >= 2 && tokens[i-1] == "(" && !isOperator(tokens[i-2])
+		default:
+			// function name { ... }
+			pendingBody = !isOperator(token) && i >= 1 && tokens[i-1] == "function"
+		}
+	}
+	return len(groups) > 0
+}
+
+func isOperator(token string) bool {
+	return len(token) == 1 && strings.Contains("\n;&|()<>", token)
+}
@@ -0,0 +1,104 @@
+package shell
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line string
+		want Statement
+	}{
+		{"", Statement{Kind: StatementBlank}},
+		{"   ", Statement{Kind: StatementBlank}},
+		{"# git shortcuts", Statement{Kind: StatementComment, Comment: "git shortcuts"}},
+		{"alias g=git", Statement{Kind: StatementAlias, Name: "g", Value: "git"}},
+		{"alias ll='ls -la'", Statement{Kind: StatementAlias, Name: "ll", Value: "ls -la", Quote: '\''}},
+		{`alias gs="git status" # short`, Statement{Kind: StatementAlias, Name: "gs", Value: "git status", Quote: '"', Comment: "short"}},
+		{"alias ..='cd ..'", Statement{Kind: StatementAlias, Name: "..", Value: "cd ..", Quote: '\''}},
+		{`export EDITOR="nvim"`, Statement{Kind: StatementExport, Name: "EDITOR", Value: "nvim", Quote: '"', Exported: true}},
+		{"export\tGOPATH=$HOME/go", Statement{Kind: StatementExport, Name: "GOPATH", Value: "$HOME/go", Exported: true}},
+		{"HISTSIZE=1000", Statement{Kind: StatementExport, Name: "HISTSIZE", Value: "1000"}},
+		{`export MSG="say \"hi\""`, Statement{Kind: StatementExport, Name: "MSG", Value: `say \"hi\"`, Quote: '"', Exported: true}},
+		{`export PATH="$HOME/bin:/opt/bin:$PATH"`, Statement{Kind: StatementPath, Name: "PATH", Value: "$HOME/bin:/opt/bin:$PATH", Quote: '"', Exported: true, Entries: []string{"$HOME/bin", "/opt/bin"}}},
+		{"export PATH=${PATH}:~/go/bin", Statement{Kind: StatementPath, Name: "PATH", Value: "${PATH}:~/go/bin", Exported: true, Entries: []string{"~/go/bin"}, Append: true}},
+		{`export PATH="/opt/bin"`, Statement{Kind: StatementOther, Name: "PATH", Value: "/opt/bin", Quote: '"', Exported: true}},
+		{`export PATH='/a:$PATH'`, Statement{Kind: StatementOther, Name: "PATH", Value: "/a:$PATH", Quote: '\'', Exported: true}},
+		{"export EDITOR", Statement{Kind: StatementOther}},
+		{"export A=1 B=2", Statement{Kind: StatementOther}},
+		{"alias g=git; alias h=hg", Statement{Kind: StatementOther}},
+		{`export EDITOR="vim`, Statement{Kind: StatementOther}},
+		{"aliased=1", Statement{Kind: StatementExport, Name: "aliased", Value: "1"}},
+		{"eval \"$(starship init bash)\"", Statement{Kind: StatementOther}},
+		{"[ -f ~/.fzf.bash ] && source ~/.fzf.bash", Statement{Kind: StatementOther}},
+	}
+
+	for _, tt := range tests {
+		got := ParseLine(tt.line)
+		tt.want.Raw = tt.line
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseLine(%q) =\n%+v\nwant\n%+v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestParseModuleLineNumbers(t *testing.T) {
+	statements := ParseModule("# env\n\nexport A=1\n")
+	if len(statements) != 3 {
+		t.Fatalf("got %d statements, want 3", len(statements))
+	}
+	for i, statement := range statements {
+		if statement.Line != i+1 {
+			t.Errorf("statement %d has Line %d", i, statement.Line)
+		}
+	}
+}
+
+func TestValueParts(t *testing.T) {
+	tests := []struct {
+		value string
+		quote byte
+		want  []ValuePart
+		ok    bool
+	}{
+		{"plain", 0, []ValuePart{{Literal: "plain"}}, true},
+		{"$HOME/go", '"', []ValuePart{{Variable: "HOME"}, {Literal: "/go"}}, true},
+		{"${XDG_CONFIG_HOME}/nvim", '"', []ValuePart{{Variable: "XDG_CONFIG_HOME"}, {Literal: "/nvim"}}, true},
+		{"~/bin", 0, []ValuePart{{Variable: "HOME"}, {Literal: "/bin"}}, true},
+		{"~/bin", '"', []ValuePart{{Literal: "~/bin"}}, true},
+		{"$HOME", '\'', []ValuePart{{Literal: "$HOME"}}, true},
+		{`cost \$5`, '"', []ValuePart{{Literal: "cost $5"}}, true},
+		{`a\nb`, '"', []ValuePart{{Literal: `a\nb`}}, true},
+		{"price $ 5", '"', []ValuePart{{Literal: "price $ 5"}}, true},
+		{"$(date)", '"', nil, false},
+		{"`date`", 0, nil, false},
+		{"${EDITOR:-vim}", '"', nil, false},
+		{"$1", '"', nil, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := ValueParts(tt.value, tt.quote)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ValueParts(%q, %q) = %+v, %v, want %+v, %v", tt.value, tt.quote, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestSimpleCommandWords(t *testing.T) {
+	tests := map[string][]string{
+		"git status":          {"git", "status"},
+		"ls -la --color=auto": {"ls", "-la", "--color=auto"},
+		"":                    nil,
+		"ls | less":           nil,
+		"echo $HOME":          nil,
+		"grep 'a b'":          nil,
+	}
+
+	for value, want := range tests {
+		got, ok := SimpleCommandWords(value)
+		if ok != (want != nil) || !reflect.DeepEqual(got, want) {
+			t.Errorf("SimpleCommandWords(%q) = %q, %v", value, got, ok)
+		}
+	}
+}
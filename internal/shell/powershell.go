@@ -0,0 +1,196 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+var pwshEscaper = strings.NewReplacer("`", "``", `"`, "`\"", "$", "`$")
+
+// pwshString renders a POSIX shell value as a PowerShell expandable string.
+func pwshString(value string, quote byte) (string, bool) {
+	parts, ok := ValueParts(value, quote)
+	if !ok {
+		return "", false
+	}
+
+	var out strings.Builder
+	for _, part := range parts {
+		if part.Variable != "" {
+			out.WriteString("${env:" + part.Variable + "}")
+			continue
+		}
+		out.WriteString(pwshEscaper.Replace(part.Literal))
+	}
+	return `"` + out.String() + `"`, true
+}
+
+// pwshAlias translates an alias into Set-Alias when it names a bare command,
+// or into a function forwarding its arguments when it also passes arguments.
+// Built-in aliases take precedence over functions, so a clashing one is
+// removed first. An alias like ls="ls --color=auto" calls the program of the
+// same name, which the function would otherwise shadow.
+func pwshAlias(name, value string) (string, bool) {
+	if !simpleWordPattern.MatchString(name) {
+		return "", false
+	}
+
+	words, ok := SimpleCommandWords(value)
+	if !ok {
+		return "", false
+	}
+
+	command := words[0]
+	switch {
+	case command == name:
+		command = fmt.Sprintf("& (Get-Command -Name %s -CommandType Application)[0]", name)
+	case len(words) == 1:
+		return fmt.Sprintf("Set-Alias -Name %s -Value %s -Force", name, command), true
+	}
+
+	body := strings.Join(append([]string{command}, words[1:]...), " ")
+	return fmt.Sprintf("Remove-Item -Path Alias:%s -Force -ErrorAction SilentlyContinue\nfunction %s { %s @args }", name, name, body), true
+}
+
+// TranslateToPowerShell rewrites the statically understood lines of a module
+// as PowerShell. Lines it cannot translate are returned for reporting.
+func TranslateToPowerShell(content string) (string, []Statement) {
+	var out strings.Builder
+	var untranslated []Statement
+
+	for _, statement := range ParseModule(content) {
+		line, ok := "", false
+
+		switch statement.Kind {
+		case StatementBlank, StatementComment:
+			line, ok = statement.Raw, true
+		case StatementExport:
+			// Values refer to variables as ${env:NAME}, so plain assignments
+			// have to go to the environment too
+			var value string
+			if value, ok = pwshString(statement.Value, statement.Quote); ok {
+				line = fmt.Sprintf("$env:%s = %s", statement.Name, value)
+			}
+		case StatementPath:
+			var entries []string
+			ok = true
+			for _, entry := range statement.Entries {
+				value, entryOK := pwshString(entry, statement.Quote)
+				ok = ok && entryOK
+				entries = append(entries, value)
+			}
+			if statement.Append {
+				entries = append([]string{"$env:PATH"}, entries...)
+			} else {
+				entries = append(entries, "$env:PATH")
+			}
+			line = fmt.Sprintf("$env:PATH = %s -join [IO.Path]::PathSeparator", strings.Join(entries, ", "))
+		case StatementAlias:
+			line, ok = pwshAlias(statement.Name, statement.Value)
+		}
+
+		if !ok {
+			untranslated = append(untranslated, statement)
+			line = "# dotwaifu: untranslated: " + statement.Raw
+		}
+		out.WriteString(line + "\n")
+	}
+
+	return out.String(), untranslated
+}
+
+func translatePowerShellModules() (string, map[string][]Statement, error) {
+	modules, err := LoadOrder()
+	if err != nil {
+		return "", nil, err
+	}
+
+	var out strings.Builder
+	untranslated := make(map[string][]Statement)
+
+	for _, module := range modules {
+		if module.HasConflict() {
+			continue
+		}
+
+		content, err := os.ReadFile(module.Path)
+		if err != nil {
+			return "", nil, err
+		}
+
+		translated, skipped := TranslateToPowerShell(string(content))
+		if len(skipped) > 0 {
+			untranslated[module.RelPath()] = skipped
+		}
+
+		rel := strings.ReplaceAll(module.RelPath(), "'", "''")
+		out.WriteString(fmt.Sprintf("\n# --- %s ---\nif (-not (Test-DotwaifuSkip '%s')) {\n%s}\n", module.RelPath(), rel, translated))
+	}
+
+	return out.String(), untranslated, nil
+}
+
+func powerShellLoadingLogic() (string, error) {
+	// PowerShell cannot source the .sh modules, so their translation is
+	// written straight into the profile
+	translated, _, err := translatePowerShellModules()
+	if err != nil {
+		return "", err
+	}
+	return "# Translated from ~/.config/dotwaifu/shell/shared; 'dotwaifu reload' regenerates it\n" +
+		powerShellSafeModeCheck + translated + powerShellCleanup, nil
+}
+
+// powerShellSafeModeCheck makes DOTWAIFU_SAFE and DOTWAIFU_SKIP work as in
+// the loader of the other shells, see safeModeCheck. Each translated module
+// is wrapped in a Test-DotwaifuSkip guard.
+const powerShellSafeModeCheck = `# DOTWAIFU_SAFE=1 starts PowerShell without any modules, and
+# DOTWAIFU_SKIP=core/scripts,projects/flutter without the listed ones
+$dotwaifuSafe = $env:DOTWAIFU_SAFE -and $env:DOTWAIFU_SAFE -ne '0'
+if ($dotwaifuSafe) { [Console]::Error.WriteLine('dotwaifu: safe mode, no modules loaded') }
+$dotwaifuSkip = "$env:DOTWAIFU_SKIP" -split ','
+function Test-DotwaifuSkip([string]$Module) {
+    $dotwaifuSafe -or $dotwaifuSkip -contains $Module -or $dotwaifuSkip -contains ($Module -replace '\.sh$') -or $dotwaifuSkip -contains ($Module -replace '/[^/]*$')
+}
+`
+
+const powerShellCleanup = `
+Remove-Item -Path Function:Test-DotwaifuSkip
+Remove-Variable -Name dotwaifuSafe, dotwaifuSkip`
+
+// RegeneratePowerShellProfile rewrites the dotwaifu block of the PowerShell
+// profile from the current modules and returns the lines that could not be
+// translated, keyed by module path relative to shell/shared.
+func RegeneratePowerShellProfile() (map[string][]Statement, error) {
+	_, untranslated, err := translatePowerShellModules()
+	if err != nil {
+		return nil, err
+	}
+
+	rcPath := GetRCFilePath("pwsh")
+	content, err := os.ReadFile(rcPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return untranslated, nil
+		}
+		return nil, err
+	}
+
+	switch {
+	case strings.Contains(string(content), integrationStartMarker):
+		block, err := GenerateRCContent("pwsh", true)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := replaceIntegrationBlock(string(content), block)
+		if err != nil {
+			return nil, err
+		}
+		return untranslated, os.WriteFile(rcPath, []byte(updated), 0644)
+	case strings.Contains(string(content), generatedMarker):
+		return untranslated, CreateNewRC("pwsh")
+	}
+
+	return untranslated, nil
+}
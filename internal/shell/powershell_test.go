@@ -0,0 +1,171 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestTranslateToPowerShell(t *testing.T) {
+	tests := []struct {
+		name         string
+		in           string
+		want         string
+		untranslated int
+	}{
+		{
+			name: "comments and blank lines",
+			in:   "# env\n\n",
+			want: "# env\n\n",
+		},
+		{
+			name: "exported variables",
+			in:   "export EDITOR=\"nvim\"\nexport GOPATH=$HOME/go\n",
+			want: "$env:EDITOR = \"nvim\"\n$env:GOPATH = \"${env:HOME}/go\"\n",
+		},
+		{
+			name: "plain variables go to the environment too",
+			in:   "NOTES=\"$HOME/notes\"\nexport NOTES_FILE=\"$NOTES/todo.md\"\n",
+			want: "$env:NOTES = \"${env:HOME}/notes\"\n$env:NOTES_FILE = \"${env:NOTES}/todo.md\"\n",
+		},
+		{
+			name: "escaping",
+			in:   "export PRICE='costs $5 or `cat`'\n",
+			want: "$env:PRICE = \"costs `$5 or ``cat``\"\n",
+		},
+		{
+			name: "prepended and appended PATH",
+			in:   "export PATH=\"$HOME/bin:$PATH\"\nexport PATH=$PATH:/opt/bin\n",
+			want: "$env:PATH = \"${env:HOME}/bin\", $env:PATH -join [IO.Path]::PathSeparator\n" +
+				"$env:PATH = $env:PATH, \"/opt/bin\" -join [IO.Path]::PathSeparator\n",
+		},
+		{
+			name: "bare command alias",
+			in:   "alias g=git\n",
+			want: "Set-Alias -Name g -Value git -Force\n",
+		},
+		{
+			name: "alias with arguments",
+			in:   "alias gs='git status'\n",
+			want: "Remove-Item -Path Alias:gs -Force -ErrorAction SilentlyContinue\nfunction gs { git status @args }\n",
+		},
+		{
+			name: "alias calling the program of the same name",
+			in:   "alias ls='ls --color=auto'\n",
+			want: "Remove-Item -Path Alias:ls -Force -ErrorAction SilentlyContinue\n" +
+				"function ls { & (Get-Command -Name ls -CommandType Application)[0] --color=auto @args }\n",
+		},
+		{
+			name: "alias to itself",
+			in:   "alias vim=vim\n",
+			want: "Remove-Item -Path Alias:vim -Force -ErrorAction SilentlyContinue\n" +
+				"function vim { & (Get-Command -Name vim -CommandType Application)[0] @args }\n",
+		},
+		{
+			name:         "alias with a pipe",
+			in:           "alias lsl='ls | less'\n",
+			want:         "# dotwaifu: untranslated: alias lsl='ls | less'\n",
+			untranslated: 1,
+		},
+		{
+			name:         "command substitution",
+			in:           "export PATH=\"$(go env GOPATH)/bin:$PATH\"\n",
+			want:         "# dotwaifu: untranslated: export PATH=\"$(go env GOPATH)/bin:$PATH\"\n",
+			untranslated: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, untranslated := TranslateToPowerShell(tt.in)
+			if got != tt.want {
+				t.Errorf("TranslateToPowerShell =\n%s\nwant\n%s", got, tt.want)
+			}
+			if len(untranslated) != tt.untranslated {
+				t.Errorf("%d untranslated lines, want %d", len(untranslated), tt.untranslated)
+			}
+		})
+	}
+}
+
+func TestRegeneratePowerShellProfile(t *testing.T) {
+	setupModules(t, map[string]string{"core/env.sh": "export EDITOR=\"vim\"\n"})
+
+	rcPath := GetRCFilePath("pwsh")
+	if err := os.MkdirAll(filepath.Dir(rcPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(rcPath, []byte("Set-PSReadLineOption -EditMode Emacs\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := AppendToExistingRC("pwsh"); err != nil {
+		t.Fatal(err)
+	}
+
+	envPath := filepath.Join(GetCoreDir(), "env.sh")
+	if err := os.WriteFile(envPath, []byte("export EDITOR=\"nvim\"\nalias lsl='ls | less'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	untranslated, err := RegeneratePowerShellProfile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(untranslated["core/env.sh"]) != 1 {
+		t.Errorf("untranslated = %v", untranslated)
+	}
+
+	content, err := os.ReadFile(rcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := string(content)
+	if !strings.HasPrefix(profile, "Set-PSReadLineOption -EditMode Emacs\n") {
+		t.Errorf("the user's profile was not kept: %q", profile)
+	}
+	if !strings.Contains(profile, "$env:EDITOR = \"nvim\"") || strings.Contains(profile, "\"vim\"") {
+		t.Errorf("profile was not regenerated: %q", profile)
+	}
+	if strings.Count(profile, integrationStartMarker) != 1 {
+		t.Errorf("profile has %d dotwaifu blocks", strings.Count(profile, integrationStartMarker))
+	}
+}
+
+func TestPowerShellProfileReportsLoadOrderErrors(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh":   "# dotwaifu: after=core/paths.sh\nexport EDITOR=\"vim\"\n",
+		"core/paths.sh": "# dotwaifu: after=core/env.sh\nexport PATH=\"/opt/bin:$PATH\"\n",
+	})
+
+	if err := CreateNewRC("pwsh"); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Errorf("CreateNewRC with an after= cycle: %v", err)
+	}
+	if _, err := os.Stat(GetRCFilePath("pwsh")); !os.IsNotExist(err) {
+		t.Errorf("a profile without the modules was written: %v", err)
+	}
+	if _, err := GenerateRCContent("pwsh", true); err == nil {
+		t.Error("GenerateRCContent with an after= cycle succeeded")
+	}
+}
+
+func TestPowerShellProfileHonorsSafeMode(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh":             "export EDITOR=\"vim\"\n",
+		"projects/flutter/env.sh": "export FLUTTER_ROOT=/opt/flutter\n",
+	})
+
+	block, err := GenerateRCContent("pwsh", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"if ($dotwaifuSafe) { [Console]::Error.WriteLine('dotwaifu: safe mode, no modules loaded') }\n",
+		"# --- core/env.sh ---\nif (-not (Test-DotwaifuSkip 'core/env.sh')) {\n$env:EDITOR = \"vim\"\n}\n",
+		"# --- projects/flutter/env.sh ---\nif (-not (Test-DotwaifuSkip 'projects/flutter/env.sh')) {\n",
+		"Remove-Item -Path Function:Test-DotwaifuSkip\n",
+	} {
+		if !strings.Contains(block, want) {
+			t.Errorf("profile block does not contain %q:\n%s", want, block)
+		}
+	}
+}
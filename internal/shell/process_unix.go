@@ -0,0 +1,18 @@
+//go:build !windows
+
+package shell
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachProcess runs cmd in a session of its own, so that an interactive
+// shell cannot take over the terminal, and makes cancelling it kill the
+// whole process group.
+func detachProcess(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
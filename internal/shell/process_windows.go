@@ -0,0 +1,9 @@
+//go:build windows
+
+package shell
+
+import "os/exec"
+
+// detachProcess is a no-op on Windows, where zsh and bash modules are not
+// loaded natively.
+func detachProcess(cmd *exec.Cmd) {}
@@ -0,0 +1,141 @@
+package shell
+
+import (
+	"bufio"
+	"context"
+	"dotwaifu/internal/config"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ModuleTiming is how long sourcing one module took.
+type ModuleTiming struct {
+	Module   Module
+	Duration time.Duration
+	// Deferred modules are lazy or belong to a project with activation
+	// rules, so they do not slow down shell startup
+	Deferred bool
+}
+
+// Profile is the result of ProfileModules.
+type Profile struct {
+	// Startup is the time until the shell started sourcing modules
+	Startup time.Duration
+	// Modules are in load order
+	Modules []ModuleTiming
+}
+
+// Total is the time the modules add to shell startup.
+func (p Profile) Total() time.Duration {
+	var total time.Duration
+	for _, timing := range p.Modules {
+		if !timing.Deferred {
+			total += timing.Duration
+		}
+	}
+	return total
+}
+
+func isDeferred(module Module) (bool, error) {
+	triggers, err := module.LazyTriggers()
+	if err != nil {
+		return false, err
+	}
+	if len(triggers) > 0 || module.Project == "" {
+		return len(triggers) > 0, nil
+	}
+
+	manifest, err := LoadProjectManifest(module.Project)
+	if err != nil {
+		return false, err
+	}
+	return manifest.Activation.IsSet(), nil
+}
+
+// ProfileTimeoutError is returned by ProfileModules when the modules take
+// longer than the timeout, usually because one of them hangs.
+type ProfileTimeoutError struct {
+	Module  string
+	Timeout time.Duration
+}
+
+func (e *ProfileTimeoutError) Error() string {
+	return fmt.Sprintf("timed out after %s while sourcing %s", e.Timeout, e.Module)
+}
+
+// ProfileModules sources every enabled module in load order in a
+// non-interactive shell and times each one, including those that are
+// normally deferred. The shell reports progress on file descriptor 3, and
+// the timestamps are taken as the reports arrive. Output of the modules is
+// discarded. The shell is killed once timeout has passed.
+func ProfileModules(shell string, timeout time.Duration) (*Profile, error) {
+	modules, err := LoadOrder()
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &Profile{}
+	var script strings.Builder
+	script.WriteString(cacheHelper)
+	script.WriteString("echo >&3\n")
+	for _, module := range modules {
+		if module.HasConflict() {
+			continue
+		}
+		deferred, err := isDeferred(module)
+		if err != nil {
+			return nil, err
+		}
+		profile.Modules = append(profile.Modules, ModuleTiming{Module: module, Deferred: deferred})
+		fmt.Fprintf(&script, "source %s >/dev/null 2>&1 3>&-\necho >&3\n", shellQuote(module.Path))
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, ModuleShell(shell), "-c", script.String())
+	cmd.Env = append(os.Environ(), "DOTWAIFU_CONFIG_ROOT="+config.GetConfigDir())
+	cmd.ExtraFiles = []*os.File{writer}
+	cmd.WaitDelay = time.Second
+	detachProcess(cmd)
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		writer.Close()
+		return nil, fmt.Errorf("starting %s: %w", ModuleShell(shell), err)
+	}
+	writer.Close()
+
+	var stamps []time.Time
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		stamps = append(stamps, time.Now())
+	}
+	cmd.Wait()
+
+	// The first stamp is taken before the first module, then one after each
+	if ctx.Err() == context.DeadlineExceeded && len(stamps) > 0 && len(stamps) <= len(profile.Modules) {
+		return nil, &ProfileTimeoutError{Module: profile.Modules[len(stamps)-1].Module.RelPath(), Timeout: timeout}
+	}
+	if len(stamps) == 0 {
+		return nil, fmt.Errorf("%s exited before sourcing any module", ModuleShell(shell))
+	}
+	if len(stamps) <= len(profile.Modules) {
+		return nil, fmt.Errorf("%s exited while sourcing %s", ModuleShell(shell), profile.Modules[len(stamps)-1].Module.RelPath())
+	}
+
+	profile.Startup = stamps[0].Sub(start)
+	for i := range profile.Modules {
+		profile.Modules[i].Duration = stamps[i+1].Sub(stamps[i])
+	}
+	return profile, nil
+}
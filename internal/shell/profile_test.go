@@ -0,0 +1,90 @@
+package shell
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProfileModules(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	setupModules(t, map[string]string{
+		"core/env.sh":             "export EDITOR=vim\n",
+		"core/scripts.sh":         "sleep 0.2\n",
+		"core/nvm.sh":             "# dotwaifu: lazy=nvm\nsleep 0.2\n",
+		"projects/flutter/env.sh": "echo noisy; sleep 0.2\n",
+	})
+	saveManifest(t, "flutter", Activation{Dirs: []string{"~/code/app"}})
+
+	profile, err := ProfileModules("bash", 10*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	timings := make(map[string]ModuleTiming)
+	for _, timing := range profile.Modules {
+		timings[timing.Module.RelPath()] = timing
+	}
+	if len(timings) != 4 {
+		t.Fatalf("profiled %d modules, want 4", len(timings))
+	}
+
+	for path, deferred := range map[string]bool{
+		"core/env.sh":             false,
+		"core/scripts.sh":         false,
+		"core/nvm.sh":             true,
+		"projects/flutter/env.sh": true,
+	} {
+		timing := timings[path]
+		if timing.Deferred != deferred {
+			t.Errorf("%s deferred = %v, want %v", path, timing.Deferred, deferred)
+		}
+		if slow := timing.Duration >= 150*time.Millisecond; slow != (path != "core/env.sh") {
+			t.Errorf("%s took %v", path, timing.Duration)
+		}
+	}
+
+	// Deferred modules do not count towards startup
+	if total := profile.Total(); total < 150*time.Millisecond || total > 400*time.Millisecond {
+		t.Errorf("Total = %v", total)
+	}
+}
+
+func TestProfileModulesReportsExit(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	setupModules(t, map[string]string{
+		"core/aliases.sh": "alias g=git\n",
+		"core/env.sh":     "exit 1\n",
+	})
+
+	if _, err := ProfileModules("bash", 10*time.Second); err == nil || !strings.Contains(err.Error(), "exited while sourcing core/env.sh") {
+		t.Errorf("ProfileModules = %v", err)
+	}
+}
+
+func TestProfileModulesTimesOut(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	setupModules(t, map[string]string{
+		"core/env.sh":     "export EDITOR=vim\n",
+		"core/scripts.sh": "sleep 30\n",
+	})
+
+	start := time.Now()
+	_, err := ProfileModules("bash", 300*time.Millisecond)
+	var timeout *ProfileTimeoutError
+	if !errors.As(err, &timeout) || timeout.Module != "core/scripts.sh" {
+		t.Errorf("ProfileModules = %v, want a timeout in core/scripts.sh", err)
+	}
+	// The sleep started by the module must not keep ProfileModules waiting
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("ProfileModules took %v", elapsed)
+	}
+}
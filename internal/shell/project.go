@@ -0,0 +1,118 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ProjectManifestName is the optional file in a project directory that
+// describes how the project is loaded.
+const ProjectManifestName = "project.yaml"
+
+// Activation lists when a project is active. A project without any rule is
+// loaded in every shell; otherwise it is only loaded while the working
+// directory matches one of the rules.
+type Activation struct {
+	// Dirs are directory globs; ~ expands to the home directory, and a
+	// relative glob matches a directory of that name anywhere. A directory
+	// also matches everything below it.
+	Dirs []string `yaml:"dirs,omitempty"`
+	// Markers are file names such as pubspec.yaml or package.json, looked
+	// up in the working directory and its parents below $HOME.
+	Markers []string `yaml:"markers,omitempty"`
+	// Remotes are globs matched against the git remote URLs of the
+	// working directory, e.g. "*github.com/acme/*".
+	Remotes []string `yaml:"remotes,omitempty"`
+}
+
+// IsSet reports whether the project is restricted to matching directories.
+func (a Activation) IsSet() bool {
+	return len(a.Dirs) > 0 || len(a.Markers) > 0 || len(a.Remotes) > 0
+}
+
+type ProjectManifest struct {
+	// Disabled projects keep their files but are not loaded
+	Disabled   bool       `yaml:"disabled,omitempty"`
+	Activation Activation `yaml:"activation,omitempty"`
+	// Lazy lists commands such as nvm or node; the project is only loaded
+	// the first time one of them runs
+	Lazy []string `yaml:"lazy,omitempty"`
+}
+
+func GetProjectDir(project string) string {
+	return filepath.Join(GetProjectsDir(), project)
+}
+
+func GetProjectManifestPath(project string) string {
+	return filepath.Join(GetProjectDir(project), ProjectManifestName)
+}
+
+// ProjectExists reports whether project has a directory under projects/.
+func ProjectExists(project string) bool {
+	info, err := os.Stat(GetProjectDir(project))
+	return err == nil && info.IsDir()
+}
+
+// ValidateProjectName rejects names that cannot be a project directory.
+func ValidateProjectName(project string) error {
+	switch {
+	case project == "":
+		return fmt.Errorf("project name cannot be empty")
+	case strings.HasPrefix(project, "."):
+		return fmt.Errorf("project name cannot start with '.'")
+	case strings.ContainsAny(project, `/\`):
+		return fmt.Errorf("project name cannot contain path separators")
+	}
+	return nil
+}
+
+// IsProjectDisabled reports whether project is disabled. A manifest that
+// cannot be read counts as enabled; doctor reports it.
+func IsProjectDisabled(project string) bool {
+	manifest, err := LoadProjectManifest(project)
+	return err == nil && manifest.Disabled
+}
+
+// SetProjectDisabled disables or re-enables project, keeping the rest of
+// its manifest.
+func SetProjectDisabled(project string, disabled bool) error {
+	manifest, err := LoadProjectManifest(project)
+	if err != nil {
+		return err
+	}
+
+	manifest.Disabled = disabled
+	return SaveProjectManifest(project, manifest)
+}
+
+// LoadProjectManifest reads the manifest of project. A project without a
+// manifest gets an empty one.
+func LoadProjectManifest(project string) (*ProjectManifest, error) {
+	manifest := &ProjectManifest{}
+
+	data, err := os.ReadFile(GetProjectManifestPath(project))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return manifest, nil
+		}
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(data, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+func SaveProjectManifest(project string, manifest *ProjectManifest) error {
+	data, err := yaml.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(GetProjectManifestPath(project), data, 0644)
+}
@@ -0,0 +1,16 @@
+package shell
+
+import "testing"
+
+func TestValidateProjectName(t *testing.T) {
+	for _, name := range []string{"flutter", "my-app", "node.js"} {
+		if err := ValidateProjectName(name); err != nil {
+			t.Errorf("ValidateProjectName(%q) = %v", name, err)
+		}
+	}
+	for _, name := range []string{"", ".", "..", ".hidden", "a/b", `a\b`} {
+		if err := ValidateProjectName(name); err == nil {
+			t.Errorf("ValidateProjectName(%q) accepted it", name)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// safeModeCheck starts the loader. DOTWAIFU_SAFE=1 gives a shell without
+// any modules, to get out of a configuration that hangs or breaks the shell.
+const safeModeCheck = `# DOTWAIFU_SAFE=1 starts the shell without any modules, and
+# DOTWAIFU_SKIP=core/scripts,projects/flutter without the listed modules and
+# projects; see 'dotwaifu bisect'
+if [ "${DOTWAIFU_SAFE:-0}" != 0 ]; then
+    echo "dotwaifu: safe mode, no modules loaded" >&2
+    return 0
+fi
+
+_dotwaifu_skip() {
+    _dotwaifu_rel=${1#"$DOTWAIFU_CONFIG_ROOT"/shell/shared/}
+    case ",$DOTWAIFU_SKIP," in
+        *",$_dotwaifu_rel,"*|*",${_dotwaifu_rel%.sh},"*|*",${_dotwaifu_rel%/*},"*) return 0 ;;
+    esac
+    return 1
+}
+`
+
+// bundleSafeModeCheck hands over to the loader, which sources the modules one
+// by one and so can leave some out.
+const bundleSafeModeCheck = `# Safe mode and skipped modules are handled by the loader
+if [ "${DOTWAIFU_SAFE:-0}" != 0 ] || [ -n "$DOTWAIFU_SKIP" ]; then
+    source "$DOTWAIFU_CONFIG_ROOT/state/loader.sh"
+    return 0
+fi
+`
+
+// StartupTest runs test in a new interactive shell, which loads the modules
+// through the RC file like a new terminal would. env is added to the
+// environment, e.g. DOTWAIFU_SKIP to leave modules out. The test fails when
+// it exits non-zero or does not finish within timeout; the shell and
+// everything it started is killed then.
+func StartupTest(shell, test string, timeout time.Duration, env ...string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, shell, "-ic", test)
+	for _, variable := range os.Environ() {
+		if !strings.HasPrefix(variable, "DOTWAIFU_SAFE=") && !strings.HasPrefix(variable, "DOTWAIFU_SKIP=") {
+			cmd.Env = append(cmd.Env, variable)
+		}
+	}
+	cmd.Env = append(cmd.Env, env...)
+	cmd.WaitDelay = time.Second
+	detachProcess(cmd)
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+	return err
+}
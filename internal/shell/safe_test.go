@@ -0,0 +1,105 @@
+package shell
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSafeMode(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh":          "export EDITOR=vim\n",
+		"projects/rust/env.sh": "export CARGO_HOME=/opt/cargo\n",
+	})
+
+	t.Setenv("DOTWAIFU_SAFE", "1")
+	got := runLoader(t, `echo "${EDITOR-unset} ${CARGO_HOME-unset}"`)
+	if got != "unset unset\n" {
+		t.Errorf("in safe mode got %q", got)
+	}
+
+	t.Setenv("DOTWAIFU_SAFE", "0")
+	if got := runLoader(t, `echo "${EDITOR-unset} ${CARGO_HOME-unset}"`); got != "vim /opt/cargo\n" {
+		t.Errorf("with DOTWAIFU_SAFE=0 got %q", got)
+	}
+}
+
+func TestSkip(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh":             "export EDITOR=vim\n",
+		"core/paths.sh":           "export PATHS=1\n",
+		"core/scripts.sh":         "export SCRIPTS=1\n",
+		"projects/rust/env.sh":    "export CARGO_HOME=/opt/cargo\n",
+		"projects/flutter/env.sh": "export FLUTTER_ROOT=/opt/flutter\n",
+	})
+	show := `echo "${EDITOR-unset} ${PATHS-unset} ${SCRIPTS-unset} ${CARGO_HOME-unset} ${FLUTTER_ROOT-unset}"`
+
+	tests := map[string]string{
+		"":                            "vim 1 1 /opt/cargo /opt/flutter\n",
+		"core/scripts":                "vim 1 unset /opt/cargo /opt/flutter\n",
+		"core/env.sh,projects/rust":   "unset 1 1 unset /opt/flutter\n",
+		"core/paths,projects/flutter": "vim unset 1 /opt/cargo unset\n",
+		"core/pat,projects/rus":       "vim 1 1 /opt/cargo /opt/flutter\n",
+	}
+	for skip, want := range tests {
+		t.Setenv("DOTWAIFU_SKIP", skip)
+		if got := runLoader(t, show); got != want {
+			t.Errorf("DOTWAIFU_SKIP=%s: got %q, want %q", skip, got, want)
+		}
+	}
+}
+
+func TestBundleHandsSkipToLoader(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh":   "export EDITOR=vim\n",
+		"core/paths.sh": "export PATHS=1\n",
+	})
+	if err := GenerateLoader(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Setenv("DOTWAIFU_SKIP", "core/env")
+	if stdout, _ := sourceBundle(t, `echo "${EDITOR-unset} ${PATHS-unset}"`); stdout != "unset 1\n" {
+		t.Errorf("got %q", stdout)
+	}
+}
+
+func TestStartupTest(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not found")
+	}
+	setupModules(t, map[string]string{
+		"core/env.sh":     "export EDITOR=vim\n",
+		"core/scripts.sh": "[ -n \"$HANG\" ] && sleep 10\n",
+	})
+	if err := CreateNewRC("bash"); err != nil {
+		t.Fatal(err)
+	}
+	if err := GenerateLoader(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The shell loads the modules through the RC file
+	if err := StartupTest("bash", `[ "$EDITOR" = vim ]`, 5*time.Second); err != nil {
+		t.Errorf("StartupTest: %v", err)
+	}
+	if err := StartupTest("bash", `[ "$EDITOR" = vim ]`, 5*time.Second, "DOTWAIFU_SKIP=core/env"); err == nil {
+		t.Error("StartupTest passed with core/env skipped")
+	}
+
+	// DOTWAIFU_SAFE from the environment of dotwaifu itself is not passed on
+	t.Setenv("DOTWAIFU_SAFE", "1")
+	if err := StartupTest("bash", `[ "$EDITOR" = vim ]`, 5*time.Second); err != nil {
+		t.Errorf("StartupTest with DOTWAIFU_SAFE set outside: %v", err)
+	}
+
+	start := time.Now()
+	err := StartupTest("bash", "true", 300*time.Millisecond, "HANG=1")
+	if err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Errorf("StartupTest of a hanging shell = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("StartupTest took %v to give up on a hanging shell", elapsed)
+	}
+}
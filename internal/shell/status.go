@@ -0,0 +1,115 @@
+package shell
+
+import (
+	"bufio"
+	"dotwaifu/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// statusHelpers isolate the modules from each other: _dotwaifu_check reports
+// a module that failed with a shell error in one line and passes on the rest
+// of what it printed. A status of 1 alone is no failure, since modules
+// commonly end with a test like [ -d ~/bin ] && PATH=~/bin:$PATH.
+const statusHelpers = `# Failing modules are reported in one line and recorded in state/status, see
+# 'dotwaifu status'; the other modules still load
+_dotwaifu_failures=""
+_dotwaifu_stderr="$DOTWAIFU_CONFIG_ROOT/state/stderr.$$"
+
+# Arguments: the module, its status, and for modules inlined into the bundle
+# the file the shell names in its errors and the line the module starts after
+_dotwaifu_check() {
+    _dotwaifu_reason=""
+    _dotwaifu_source=${3:-$1}
+    while IFS= read -r _dotwaifu_line || [ -n "$_dotwaifu_line" ]; do
+        case "$_dotwaifu_line" in
+            "$_dotwaifu_source:"*|bash:*|zsh:*) [ "$2" -ne 0 ] && { _dotwaifu_reason=$_dotwaifu_line; continue; } ;;
+        esac
+        printf '%s\n' "$_dotwaifu_line" >&2
+    done <"$_dotwaifu_stderr"
+    [ -n "$_dotwaifu_reason" ] || return 0
+    _dotwaifu_module=${1#"$DOTWAIFU_CONFIG_ROOT"/shell/shared/}
+    _dotwaifu_reason=${_dotwaifu_reason#*"$_dotwaifu_source":}
+    _dotwaifu_reason=${_dotwaifu_reason# }
+    _dotwaifu_reason=${_dotwaifu_reason#bash: }
+    if [ -n "$4" ]; then
+        _dotwaifu_lineno=${_dotwaifu_reason#line }
+        _dotwaifu_lineno=${_dotwaifu_lineno%%:*}
+        case "$_dotwaifu_lineno" in
+            ''|*[!0-9]*) ;;
+            *) _dotwaifu_reason=${_dotwaifu_reason%%"$_dotwaifu_lineno"*}$((_dotwaifu_lineno - $4))${_dotwaifu_reason#*"$_dotwaifu_lineno"} ;;
+        esac
+    fi
+    echo "dotwaifu: $_dotwaifu_module failed: $_dotwaifu_reason" >&2
+    _dotwaifu_failures="$_dotwaifu_failures$_dotwaifu_module` + "\t" + `$_dotwaifu_reason
+"
+}
+
+# Writes the failures of this shell start to state/status, read by
+# 'dotwaifu status' and 'dotwaifu doctor'
+_dotwaifu_save_status() {
+    printf '# shell: %s\n%s' "${ZSH_VERSION:+zsh}${BASH_VERSION:+bash}" "$_dotwaifu_failures" >"$DOTWAIFU_CONFIG_ROOT/state/status"
+    command rm -f "$_dotwaifu_stderr"
+}
+`
+
+// GetStatusPath is where the loader records the modules that failed to load.
+func GetStatusPath() string {
+	return filepath.Join(config.GetStateDir(), "status")
+}
+
+// ModuleFailure is a module that failed to load, with the last error it
+// printed.
+type ModuleFailure struct {
+	Module string
+	Reason string
+}
+
+// LoaderStatus is what the loader recorded on the last shell start.
+type LoaderStatus struct {
+	Time     time.Time
+	Shell    string
+	Failures []ModuleFailure
+}
+
+// ChangedSince reports whether the failed module was edited or removed after
+// the status was recorded, so the failure may be fixed already.
+func (s LoaderStatus) ChangedSince(failure ModuleFailure) bool {
+	info, err := os.Stat(filepath.Join(GetSharedDir(), filepath.FromSlash(failure.Module)))
+	return err != nil || info.ModTime().After(s.Time)
+}
+
+// ReadStatus returns the status of the last shell start, or nil if no shell
+// has started with the current loader yet.
+func ReadStatus() (*LoaderStatus, error) {
+	path := GetStatusPath()
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	status := &LoaderStatus{Time: info.ModTime()}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if shell, ok := strings.CutPrefix(line, "# shell: "); ok {
+			status.Shell = shell
+			continue
+		}
+		if module, reason, ok := strings.Cut(line, "\t"); ok {
+			status.Failures = append(status.Failures, ModuleFailure{Module: module, Reason: reason})
+		}
+	}
+	return status, scanner.Err()
+}
@@ -0,0 +1,76 @@
+package shell
+
+import (
+	"dotwaifu/internal/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoaderIsolatesFailingModules(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/aliases.sh": "if true; then\n",
+		"core/env.sh":     "export BEFORE=1\ndotwaifu_no_such_command\n",
+		"core/paths.sh":   "echo 'a warning' >&2\nexport PATHS=1\n",
+		"core/scripts.sh": "[ -d /nonexistent ] && export SCRIPTS=1\n",
+	})
+
+	got := runLoader(t, `echo "$BEFORE $PATHS ${SCRIPTS-unset}"`)
+	if got != "1 1 unset\n" {
+		t.Errorf("after sourcing the loader got %q", got)
+	}
+
+	status, err := ReadStatus()
+	if err != nil || status == nil {
+		t.Fatalf("ReadStatus = %v, %v", status, err)
+	}
+	if status.Shell != "bash" {
+		t.Errorf("shell = %q", status.Shell)
+	}
+	want := []ModuleFailure{
+		{"core/aliases.sh", "line 2: syntax error: unexpected end of file"},
+		{"core/env.sh", "line 2: dotwaifu_no_such_command: command not found"},
+	}
+	if len(status.Failures) != len(want) {
+		t.Fatalf("failures = %+v, want %+v", status.Failures, want)
+	}
+	for i, failure := range status.Failures {
+		if failure.Module != want[i].Module || !strings.HasPrefix(failure.Reason, want[i].Reason) {
+			t.Errorf("failure %d = %+v, want %+v", i, failure, want[i])
+		}
+		if status.ChangedSince(failure) {
+			t.Errorf("%s changed since the status was recorded", failure.Module)
+		}
+	}
+
+	leftovers, _ := filepath.Glob(filepath.Join(config.GetStateDir(), "stderr.*"))
+	if len(leftovers) > 0 {
+		t.Errorf("stderr files left behind: %v", leftovers)
+	}
+}
+
+func TestLoaderReportsFailuresOnStderr(t *testing.T) {
+	setupModules(t, map[string]string{
+		"core/env.sh":   "dotwaifu_no_such_command\n",
+		"core/paths.sh": "echo 'a warning' >&2\n",
+	})
+
+	runLoader(t, `exec 2>"$HOME/stderr"; source "$DOTWAIFU_CONFIG_ROOT/state/loader.sh"`)
+	home, _ := os.UserHomeDir()
+	stderr, err := os.ReadFile(filepath.Join(home, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "dotwaifu: core/env.sh failed: line 1: dotwaifu_no_such_command: command not found\na warning\n"
+	if string(stderr) != want {
+		t.Errorf("stderr = %q, want %q", stderr, want)
+	}
+}
+
+func TestReadStatusWithoutShellStart(t *testing.T) {
+	setupModules(t, nil)
+	if status, err := ReadStatus(); status != nil || err != nil {
+		t.Errorf("ReadStatus = %+v, %v", status, err)
+	}
+}
@@ -2,8 +2,10 @@ package shell
 
 import (
 	"dotwaifu/internal/config"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 func CreateBasicStructure() error {
@@ -21,16 +23,14 @@ func CreateBasicStructure() error {
 		}
 	}
 
-	coreFiles := map[string]string{
-		"paths.sh":   "# Global PATH modifications\n# Example: export PATH=\"$HOME/bin:$PATH\"\n",
-		"aliases.sh": "# Global aliases\n# Example: alias ll=\"ls -la\"\n",
-		"env.sh":     "# Global environment variables\n# Example: export EDITOR=\"code\"\n",
-		"scripts.sh": "# Global utility scripts\n# Example: function mkcd() { mkdir -p \"$1\" && cd \"$1\"; }\n",
+	types, err := config.LoadTypes()
+	if err != nil {
+		return err
 	}
 
 	coreDir := filepath.Join(configDir, "shell", "shared", "core")
-	for filename, content := range coreFiles {
-		filePath := filepath.Join(coreDir, filename)
+	for _, t := range types {
+		filePath := filepath.Join(coreDir, t.Name+".sh")
 
 		// Only create file if it doesn't exist to preserve user content
 		if _, err := os.Stat(filePath); err == nil {
@@ -38,7 +38,7 @@ func CreateBasicStructure() error {
 			continue
 		}
 
-		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		if err := os.WriteFile(filePath, []byte(t.CoreHeader()), 0644); err != nil {
 			return err
 		}
 	}
@@ -46,6 +46,25 @@ func CreateBasicStructure() error {
 	return nil
 }
 
+// ValidateStructure checks that dir contains the shell/shared/core layout
+// that the loader expects.
+func ValidateStructure(dir string) error {
+	coreDir := filepath.Join(dir, "shell", "shared", "core")
+	info, err := os.Stat(coreDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("%s is not a dotwaifu configuration: missing shell/shared/core", dir)
+		}
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", coreDir)
+	}
+
+	return nil
+}
+
 func CreateExampleFiles() error {
 	configDir := config.GetConfigDir()
 	examplesDir := filepath.Join(configDir, "shell", "templates", "examples")
@@ -134,18 +153,14 @@ func CreateProjectConfig(projectName, configType string) error {
 		return err
 	}
 
-	var content string
-	switch configType {
-	case "paths":
-		content = "# " + projectName + " PATH modifications\n"
-	case "aliases":
-		content = "# " + projectName + " aliases\n"
-	case "env":
-		content = "# " + projectName + " environment variables\n"
-	case "scripts":
-		content = "# " + projectName + " utility scripts\n"
-	default:
-		content = "# " + projectName + " " + configType + "\n"
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	content := "# " + projectName + " " + configType + "\n"
+	if t, ok := cfg.LookupType(configType); ok {
+		content = t.ProjectHeader(projectName)
 	}
 
 	filePath := filepath.Join(projectDir, configType+".sh")
@@ -157,4 +172,39 @@ func CreateProjectConfig(projectName, configType string) error {
 	}
 
 	return os.WriteFile(filePath, []byte(content), 0644)
-}
\ No newline at end of file
+}
+
+// AppendToModule appends content to a core module (empty projectName) or a
+// project module, creating the module first if needed.
+func AppendToModule(projectName, configType, content string) error {
+	var filePath string
+	if projectName != "" {
+		if err := CreateProjectConfig(projectName, configType); err != nil {
+			return err
+		}
+		filePath = filepath.Join(GetProjectsDir(), projectName, configType+".sh")
+	} else {
+		if err := CreateBasicStructure(); err != nil {
+			return err
+		}
+		filePath = filepath.Join(GetCoreDir(), configType+".sh")
+	}
+
+	existing, err := os.ReadFile(filePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if len(existing) > 0 && !strings.HasSuffix(string(existing), "\n") {
+		content = "\n" + content
+	}
+
+	file, err := os.OpenFile(filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(content)
+	return err
+}
@@ -0,0 +1,38 @@
+package shell
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// ModuleShell returns the shell used to check or run modules outside the
+// user's interactive shell. Modules are POSIX shell, so everything but zsh
+// uses bash.
+func ModuleShell(shell string) string {
+	if shell == "zsh" {
+		return "zsh"
+	}
+	return "bash"
+}
+
+// CheckSyntax parses path with '<shell> -n' without running it. The error
+// carries the first line of the shell's own message, without the file name.
+func CheckSyntax(shell, path string) error {
+	checker := ModuleShell(shell)
+	if _, err := exec.LookPath(checker); err != nil {
+		return fmt.Errorf("%s not found: %w", checker, err)
+	}
+
+	output, err := exec.Command(checker, "-n", path).CombinedOutput()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(output) > 0 {
+			message, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+			return errors.New(strings.TrimPrefix(message, path+": "))
+		}
+		return err
+	}
+	return nil
+}